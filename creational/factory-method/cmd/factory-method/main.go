@@ -1,16 +1,198 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
 	"factory-method/internal/payment/factory"
-	"factory-method/internal/payment/processor"
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/wal"
+	"factory-method/internal/payment/routing"
+	"factory-method/internal/payment/vault"
+	"factory-method/internal/payment/webhook"
 	"factory-method/pkg/api"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	paypalFactory := factory.NewPaypalGatewayFactory()
-	stripeFactory := factory.NewStripeGatewayFactory()
+	addr := flag.String("addr", envOrDefault("PAYMENTS_ADDR", ":8080"), "HTTP listen address")
+	defaultProvider := flag.String("default-provider", envOrDefault("PAYMENTS_DEFAULT_PROVIDER", ""), "provider used when a request does not specify one")
+	simulateFaults := flag.Bool("simulate-faults", true, "inject simulated network failures and latency into the stores")
+	failover := flag.String("failover", envOrDefault("PAYMENTS_FAILOVER", ""), "comma-separated provider order for requests without a provider; transient failures fail over to the next one")
+	routingRules := flag.String("routing-rules", envOrDefault("PAYMENTS_ROUTING_RULES", ""), "JSON file with rules that pick a provider for requests without one")
+	retryAttempts := flag.Int("retry-attempts", engine.DefaultRetryPolicy().MaxAttempts, "attempts per store operation for transient failures (1 disables retries)")
+	breakerFailures := flag.Int("breaker-failures", gateway.DefaultBreakerConfig().FailureThreshold, "consecutive transient failures that open a provider's circuit breaker (0 disables it)")
+	breakerCooldown := flag.Duration("breaker-cooldown", gateway.DefaultBreakerConfig().Cooldown, "time an open circuit breaker waits before probing the provider again")
+	faultSeed := flag.Int64("fault-seed", 0, "seed for the fault injector RNG (0 picks a random seed)")
+	storeDir := flag.String("store-dir", envOrDefault("PAYMENTS_STORE_DIR", ""), "directory for durable transaction logs (in-memory when empty)")
+	walSync := flag.String("wal-sync", string(wal.SyncAlways), "write-ahead log fsync policy: always, interval or never")
+	webhookWorkers := flag.Int("webhook-workers", 4, "background workers delivering webhook events")
+	webhookAttempts := flag.Int("webhook-attempts", webhook.DefaultRetryPolicy().MaxAttempts, "delivery attempts per webhook event before it is dead-lettered")
+	flag.Parse()
+
+	faultConfig := gateway.DisabledFaultConfig()
+	if *simulateFaults {
+		faultConfig = gateway.DefaultFaultConfig()
+		faultConfig.Seed = *faultSeed
+	}
+
+	retryPolicy := engine.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *retryAttempts
+
+	breakerConfig := gateway.DefaultBreakerConfig()
+	breakerConfig.FailureThreshold = *breakerFailures
+	breakerConfig.Cooldown = *breakerCooldown
+	if *breakerFailures <= 0 {
+		breakerConfig = gateway.DisabledBreakerConfig()
+	}
+
+	cardVault, err := newVault()
+	if err != nil {
+		log.Fatalf("card vault: %v", err)
+	}
+
+	webhookPolicy := webhook.DefaultRetryPolicy()
+	webhookPolicy.MaxAttempts = *webhookAttempts
+
+	dispatcher := webhook.NewDispatcher(
+		webhook.WithWorkers(*webhookWorkers),
+		webhook.WithRetryPolicy(webhookPolicy),
+	)
+	dispatcher.Start()
+
+	factoryOptions := []factory.Option{
+		factory.WithFaultConfig(faultConfig),
+		factory.WithRetryPolicy(retryPolicy),
+		factory.WithCircuitBreaker(breakerConfig),
+		factory.WithCardVault(cardVault),
+		factory.WithCommitListener(dispatcher.Publish),
+	}
+
+	if *storeDir != "" {
+		walOptions := wal.DefaultOptions()
+		walOptions.SyncPolicy = wal.SyncPolicy(*walSync)
+		factoryOptions = append(factoryOptions, factory.WithFileStore(*storeDir, walOptions))
+	}
+
+	registry := factory.NewDefaultRegistry(factoryOptions...)
+
+	if *defaultProvider != "" {
+		if _, err := registry.Lookup(*defaultProvider); err != nil {
+			log.Fatalf("invalid default provider: %v", err)
+		}
+	}
+
+	var failoverProviders []api.ProviderType
+	for _, name := range strings.Split(*failover, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := registry.Lookup(name); err != nil {
+			log.Fatalf("invalid failover provider: %v", err)
+		}
+		failoverProviders = append(failoverProviders, api.ProviderType(name))
+	}
+
+	handlerOptions := []api.HandlerOption{
+		api.WithDefaultProvider(api.ProviderType(*defaultProvider)),
+		api.WithFailover(failoverProviders...),
+		api.WithVault(cardVault),
+		api.WithWebhooks(dispatcher),
+	}
+
+	if *routingRules != "" {
+		rules, err := routing.LoadRulesFile(*routingRules)
+		if err != nil {
+			log.Fatalf("load routing rules: %v", err)
+		}
+
+		strategy, err := routing.NewRuleEngine(rules, registry)
+		if err != nil {
+			log.Fatalf("routing rules: %v", err)
+		}
+
+		handlerOptions = append(handlerOptions, api.WithRoutingStrategy(strategy))
+	}
+
+	handler := api.NewHandler(registry, handlerOptions...)
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           api.NewHTTPHandler(handler),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		log.Printf("listening on %s", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("shutting down")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown failed: %v", err)
+	}
+
+	if err := dispatcher.Close(shutdownCtx); err != nil {
+		log.Printf("webhook dispatcher shutdown: %v", err)
+	}
+
+	if err := registry.Close(); err != nil {
+		log.Printf("transaction store shutdown: %v", err)
+	}
+}
+
+func newVault() (*vault.Vault, error) {
+	log.Println("card vault keeps tokens in memory; issued card tokens do not survive a restart")
+
+	encoded := os.Getenv("PAYMENTS_VAULT_KEY")
+	if encoded == "" {
+		log.Println("PAYMENTS_VAULT_KEY is not set; using an ephemeral vault key")
+
+		key, err := vault.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		return vault.New(key)
+	}
+
+	key, err := vault.ParseKey(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return vault.New(key)
+}
 
-	paypalProcessor := processor.NewProcessor(paypalFactory)
-	stripeProcessor := processor.NewProcessor(stripeFactory)
-	_ = api.NewHandler(paypalProcessor, stripeProcessor)
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
 }
@@ -0,0 +1,134 @@
+package card
+
+import (
+	"slices"
+	"strconv"
+)
+
+type Brand string
+
+const (
+	Unknown    Brand = "unknown"
+	Visa       Brand = "visa"
+	Mastercard Brand = "mastercard"
+	Amex       Brand = "amex"
+	Discover   Brand = "discover"
+	JCB        Brand = "jcb"
+	DinersClub Brand = "diners_club"
+	UnionPay   Brand = "unionpay"
+	Maestro    Brand = "maestro"
+)
+
+type binRange struct {
+	from   int
+	to     int
+	digits int
+}
+
+type brandRule struct {
+	brand   Brand
+	ranges  []binRange
+	lengths []int
+	cvv     int
+}
+
+var brandRules = []brandRule{
+	{
+		brand:   Amex,
+		ranges:  []binRange{{34, 34, 2}, {37, 37, 2}},
+		lengths: []int{15},
+		cvv:     4,
+	},
+	{
+		brand:   DinersClub,
+		ranges:  []binRange{{300, 305, 3}, {36, 36, 2}, {38, 39, 2}},
+		lengths: []int{14, 15, 16, 17, 18, 19},
+		cvv:     3,
+	},
+	{
+		brand:   JCB,
+		ranges:  []binRange{{3528, 3589, 4}},
+		lengths: []int{16, 17, 18, 19},
+		cvv:     3,
+	},
+	{
+		brand:   Maestro,
+		ranges:  []binRange{{5018, 5018, 4}, {5020, 5020, 4}, {5038, 5038, 4}, {5893, 5893, 4}, {6304, 6304, 4}, {6759, 6759, 4}, {6761, 6763, 4}},
+		lengths: []int{12, 13, 14, 15, 16, 17, 18, 19},
+		cvv:     3,
+	},
+	{
+		brand:   Mastercard,
+		ranges:  []binRange{{51, 55, 2}, {2221, 2720, 4}},
+		lengths: []int{16},
+		cvv:     3,
+	},
+	{
+		brand:   Discover,
+		ranges:  []binRange{{6011, 6011, 4}, {644, 649, 3}, {65, 65, 2}},
+		lengths: []int{16, 17, 18, 19},
+		cvv:     3,
+	},
+	{
+		brand:   UnionPay,
+		ranges:  []binRange{{62, 62, 2}},
+		lengths: []int{16, 17, 18, 19},
+		cvv:     3,
+	},
+	{
+		brand:   Visa,
+		ranges:  []binRange{{4, 4, 1}},
+		lengths: []int{13, 16, 19},
+		cvv:     3,
+	},
+}
+
+func DetectBrand(number string) Brand {
+	if rule, ok := ruleFor(Normalize(number)); ok {
+		return rule.brand
+	}
+	return Unknown
+}
+
+func (b Brand) ValidLength(length int) bool {
+	for _, rule := range brandRules {
+		if rule.brand == b {
+			return slices.Contains(rule.lengths, length)
+		}
+	}
+	return length >= 12 && length <= 19
+}
+
+func (b Brand) CVVLength() int {
+	for _, rule := range brandRules {
+		if rule.brand == b {
+			return rule.cvv
+		}
+	}
+	return 0
+}
+
+func ruleFor(number string) (brandRule, bool) {
+	if !IsDigits(number) {
+		return brandRule{}, false
+	}
+
+	for _, rule := range brandRules {
+		for _, r := range rule.ranges {
+			if len(number) < r.digits {
+				continue
+			}
+
+			prefix, err := strconv.Atoi(number[:r.digits])
+			if err != nil {
+				continue
+			}
+
+			if prefix >= r.from && prefix <= r.to {
+				return rule, true
+			}
+		}
+	}
+
+	return brandRule{}, false
+}
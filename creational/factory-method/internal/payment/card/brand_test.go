@@ -0,0 +1,107 @@
+package card
+
+import "testing"
+
+func TestDetectBrand(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   Brand
+	}{
+		{"4", Visa},
+		{"51", Mastercard},
+		{"55", Mastercard},
+		{"2221", Mastercard},
+		{"2720", Mastercard},
+		{"2721", Unknown},
+		{"34", Amex},
+		{"37", Amex},
+		{"300", DinersClub},
+		{"305", DinersClub},
+		{"306", Unknown},
+		{"36", DinersClub},
+		{"38", DinersClub},
+		{"3528", JCB},
+		{"3589", JCB},
+		{"3527", Unknown},
+		{"6011", Discover},
+		{"644", Discover},
+		{"649", Discover},
+		{"65", Discover},
+		{"62", UnionPay},
+		{"6221", UnionPay},
+		{"5018", Maestro},
+		{"5020", Maestro},
+		{"5038", Maestro},
+		{"5893", Maestro},
+		{"6304", Maestro},
+		{"6759", Maestro},
+		{"6761", Maestro},
+		{"6763", Maestro},
+		{"6764", Unknown},
+		{"6012", Unknown},
+		{"643", Unknown},
+		{"50", Unknown},
+		{"1", Unknown},
+	}
+
+	for _, tt := range tests {
+		number := withCheckDigit(tt.prefix, 16)
+		if got := DetectBrand(number); got != tt.want {
+			t.Errorf("DetectBrand(%s) = %s, want %s", number, got, tt.want)
+		}
+	}
+
+	if got := DetectBrand("4242 4242 4242 4242"); got != Visa {
+		t.Errorf("DetectBrand with spaces = %s, want %s", got, Visa)
+	}
+	if got := DetectBrand("4242x"); got != Unknown {
+		t.Errorf("DetectBrand(4242x) = %s, want %s", got, Unknown)
+	}
+}
+
+func TestBrandValidLength(t *testing.T) {
+	tests := []struct {
+		brand   Brand
+		valid   []int
+		invalid []int
+	}{
+		{Visa, []int{13, 16, 19}, []int{12, 14, 15, 17, 18}},
+		{Mastercard, []int{16}, []int{15, 17}},
+		{Amex, []int{15}, []int{14, 16}},
+		{DinersClub, []int{14, 19}, []int{13, 20}},
+		{JCB, []int{16, 19}, []int{15, 20}},
+		{Discover, []int{16, 19}, []int{15, 20}},
+		{UnionPay, []int{16, 19}, []int{15, 20}},
+		{Maestro, []int{12, 19}, []int{11, 20}},
+		{Unknown, []int{12, 19}, []int{11, 20}},
+	}
+
+	for _, tt := range tests {
+		for _, length := range tt.valid {
+			if !tt.brand.ValidLength(length) {
+				t.Errorf("%s.ValidLength(%d) = false, want true", tt.brand, length)
+			}
+		}
+		for _, length := range tt.invalid {
+			if tt.brand.ValidLength(length) {
+				t.Errorf("%s.ValidLength(%d) = true, want false", tt.brand, length)
+			}
+		}
+	}
+}
+
+func TestBrandCVVLength(t *testing.T) {
+	for brand, want := range map[Brand]int{
+		Amex:       4,
+		Visa:       3,
+		Mastercard: 3,
+		Discover:   3,
+		UnionPay:   3,
+		Maestro:    3,
+		Unknown:    0,
+	} {
+		if got := brand.CVVLength(); got != want {
+			t.Errorf("%s.CVVLength() = %d, want %d", brand, got, want)
+		}
+	}
+}
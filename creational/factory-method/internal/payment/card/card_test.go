@@ -0,0 +1,55 @@
+package card
+
+import (
+	"strings"
+	"testing"
+)
+
+func withCheckDigit(prefix string, length int) string {
+	body := prefix + strings.Repeat("0", length-len(prefix)-1)
+
+	for digit := range 10 {
+		if number := body + string(rune('0'+digit)); Luhn(number) {
+			return number
+		}
+	}
+
+	panic("no check digit for " + body)
+}
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4242424242424242", true},
+		{"4242424242424241", false},
+		{"5555555555554444", true},
+		{"378282246310005", true},
+		{"6011111111111117", true},
+		{"0", true},
+		{"18", true},
+		{"19", false},
+		{"", false},
+		{"4242-4242", false},
+		{"42a2424242424242", false},
+	}
+
+	for _, tt := range tests {
+		if got := Luhn(tt.number); got != tt.want {
+			t.Errorf("Luhn(%q) = %t, want %t", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{
+		" 4242 4242 4242 4242 ": "4242424242424242",
+		"4242-4242-4242-4242":   "4242424242424242",
+		"4242.4242":             "4242.4242",
+	} {
+		if got := Normalize(input); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
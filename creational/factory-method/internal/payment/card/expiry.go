@@ -0,0 +1,51 @@
+package card
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpiry = errors.New("expiry date must be in MM/YY or MM/YYYY format")
+
+type Expiry struct {
+	Month time.Month
+	Year  int
+}
+
+func ParseExpiry(value string) (Expiry, error) {
+	month, year, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Expiry{}, ErrInvalidExpiry
+	}
+
+	month = strings.TrimSpace(month)
+	year = strings.TrimSpace(year)
+
+	if len(month) != 2 || !IsDigits(month) || (len(year) != 2 && len(year) != 4) || !IsDigits(year) {
+		return Expiry{}, ErrInvalidExpiry
+	}
+
+	m, _ := strconv.Atoi(month)
+	if m < 1 || m > 12 {
+		return Expiry{}, ErrInvalidExpiry
+	}
+
+	y, _ := strconv.Atoi(year)
+	if len(year) == 2 {
+		y += 2000
+	}
+
+	return Expiry{Month: time.Month(m), Year: y}, nil
+}
+
+func (e Expiry) Expired(now time.Time) bool {
+	firstInvalidDay := time.Date(e.Year, e.Month+1, 1, 0, 0, 0, 0, time.UTC)
+	return !now.UTC().Before(firstInvalidDay)
+}
+
+func (e Expiry) String() string {
+	return fmt.Sprintf("%02d/%02d", int(e.Month), e.Year%100)
+}
@@ -0,0 +1,137 @@
+package card
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+const (
+	FieldNumber = "card_number"
+	FieldHolder = "card_holder"
+	FieldExpiry = "expiry_date"
+	FieldCVV    = "cvv"
+)
+
+type Validator struct {
+	acceptedBrands []Brand
+	now            func() time.Time
+}
+
+type Option func(*Validator)
+
+func WithAcceptedBrands(brands ...Brand) Option {
+	return func(v *Validator) {
+		v.acceptedBrands = brands
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(v *Validator) {
+		if now != nil {
+			v.now = now
+		}
+	}
+}
+
+func NewValidator(opts ...Option) *Validator {
+	v := &Validator{
+		now: func() time.Time { return time.Now().UTC() },
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+func (v *Validator) Accepts(brand Brand) bool {
+	return len(v.acceptedBrands) == 0 || slices.Contains(v.acceptedBrands, brand)
+}
+
+func (v *Validator) Validate(c Card) error {
+	errs := &gateway.ValidationError{}
+
+	brand := v.validateNumber(errs, c.Number)
+	v.validateHolder(errs, c.Holder)
+	v.validateExpiry(errs, c.Expiry)
+	v.validateCVV(errs, c.CVV, brand)
+
+	return errs.ErrOrNil()
+}
+
+func (v *Validator) validateNumber(errs *gateway.ValidationError, raw string) Brand {
+	number := Normalize(raw)
+
+	switch {
+	case number == "":
+		errs.Add(FieldNumber, gateway.CodeRequired, "card number is required")
+		return Unknown
+	case !IsDigits(number):
+		errs.Add(FieldNumber, gateway.CodeInvalidFormat, "card number must contain only digits")
+		return Unknown
+	}
+
+	brand := DetectBrand(number)
+
+	switch {
+	case !brand.ValidLength(len(number)):
+		errs.Add(FieldNumber, gateway.CodeInvalidLength, fmt.Sprintf("card number has invalid length %d", len(number)))
+	case !Luhn(number):
+		errs.Add(FieldNumber, gateway.CodeChecksum, "card number failed checksum validation")
+	case !v.Accepts(brand):
+		errs.Add(FieldNumber, gateway.CodeUnsupportedBrand, fmt.Sprintf("card brand %q is not accepted", brand))
+	}
+
+	return brand
+}
+
+func (v *Validator) validateHolder(errs *gateway.ValidationError, holder string) {
+	if strings.TrimSpace(holder) == "" {
+		errs.Add(FieldHolder, gateway.CodeRequired, "card holder name is required")
+	}
+}
+
+func (v *Validator) validateExpiry(errs *gateway.ValidationError, raw string) {
+	if strings.TrimSpace(raw) == "" {
+		errs.Add(FieldExpiry, gateway.CodeRequired, "expiry date is required")
+		return
+	}
+
+	expiry, err := ParseExpiry(raw)
+	if err != nil {
+		errs.Add(FieldExpiry, gateway.CodeInvalidFormat, err.Error())
+		return
+	}
+
+	if expiry.Expired(v.now()) {
+		errs.Add(FieldExpiry, gateway.CodeExpired, fmt.Sprintf("card expired at the end of %s", expiry))
+	}
+}
+
+func (v *Validator) validateCVV(errs *gateway.ValidationError, cvv string, brand Brand) {
+	switch {
+	case cvv == "":
+		errs.Add(FieldCVV, gateway.CodeRequired, "CVV is required")
+		return
+	case !IsDigits(cvv):
+		errs.Add(FieldCVV, gateway.CodeInvalidFormat, "CVV must contain only digits")
+		return
+	}
+
+	expected := brand.CVVLength()
+	if expected == 0 {
+		if len(cvv) != 3 && len(cvv) != 4 {
+			errs.Add(FieldCVV, gateway.CodeInvalidLength, "CVV must be 3 or 4 digits")
+		}
+		return
+	}
+
+	if len(cvv) != expected {
+		errs.Add(FieldCVV, gateway.CodeInvalidLength, fmt.Sprintf("CVV must be %d digits for %s cards", expected, brand))
+	}
+}
@@ -0,0 +1,148 @@
+package card
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+func fieldCodes(t *testing.T, err error) map[string]string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var errs *gateway.ValidationError
+	if !errors.As(err, &errs) {
+		t.Fatalf("Validate = %v, want *gateway.ValidationError", err)
+	}
+
+	codes := make(map[string]string, len(errs.Fields))
+	for _, field := range errs.Fields {
+		codes[field.Field] = field.Code
+	}
+	return codes
+}
+
+func TestValidatorExpiryAtMonthBoundary(t *testing.T) {
+	lastInstant := time.Date(2030, time.June, 30, 23, 59, 59, 999999999, time.UTC)
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		expiry string
+		want   string
+	}{
+		{name: "last instant of month", now: lastInstant, expiry: "06/30"},
+		{name: "first instant of next month", now: lastInstant.Add(time.Nanosecond), expiry: "06/30", want: gateway.CodeExpired},
+		{name: "four digit year", now: lastInstant, expiry: "06/2030"},
+		{name: "previous month", now: lastInstant, expiry: "05/30", want: gateway.CodeExpired},
+		{name: "december rolls into next year", now: time.Date(2030, time.December, 31, 12, 0, 0, 0, time.UTC), expiry: "12/30"},
+		{name: "other time zone", now: time.Date(2030, time.July, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), expiry: "06/30"},
+		{name: "month out of range", now: lastInstant, expiry: "13/30", want: gateway.CodeInvalidFormat},
+		{name: "missing separator", now: lastInstant, expiry: "0630", want: gateway.CodeInvalidFormat},
+		{name: "missing", now: lastInstant, expiry: " ", want: gateway.CodeRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(WithClock(func() time.Time { return tt.now }))
+
+			err := v.Validate(Card{Number: "4242424242424242", Holder: "Jane Doe", Expiry: tt.expiry, CVV: "123"})
+			if got := fieldCodes(t, err)[FieldExpiry]; got != tt.want {
+				t.Fatalf("expiry code = %q, want %q (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidatorCardNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		number   string
+		accepted []Brand
+		want     string
+	}{
+		{name: "visa", number: "4242424242424242"},
+		{name: "formatted", number: "4242-4242 4242-4242"},
+		{name: "visa 13 digits", number: withCheckDigit("4", 13)},
+		{name: "visa 15 digits", number: withCheckDigit("4", 15), want: gateway.CodeInvalidLength},
+		{name: "amex 16 digits", number: withCheckDigit("37", 16), want: gateway.CodeInvalidLength},
+		{name: "maestro 12 digits", number: withCheckDigit("6759", 12)},
+		{name: "unknown 11 digits", number: withCheckDigit("1", 11), want: gateway.CodeInvalidLength},
+		{name: "checksum", number: "4242424242424241", want: gateway.CodeChecksum},
+		{name: "letters", number: "4242abcd42424242", want: gateway.CodeInvalidFormat},
+		{name: "missing", number: "", want: gateway.CodeRequired},
+		{name: "accepted brand", number: "5555555555554444", accepted: []Brand{Visa, Mastercard}},
+		{name: "rejected brand", number: "378282246310005", accepted: []Brand{Visa, Mastercard}, want: gateway.CodeUnsupportedBrand},
+		{name: "rejected unknown brand", number: withCheckDigit("1", 16), accepted: []Brand{Visa}, want: gateway.CodeUnsupportedBrand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(WithAcceptedBrands(tt.accepted...))
+
+			cvv := "123"
+			if DetectBrand(tt.number) == Amex {
+				cvv = "1234"
+			}
+
+			err := v.Validate(Card{Number: tt.number, Holder: "Jane Doe", Expiry: "12/40", CVV: cvv})
+			if got := fieldCodes(t, err)[FieldNumber]; got != tt.want {
+				t.Fatalf("card number code = %q, want %q (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidatorCVVLengthPerBrand(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+		cvv    string
+		want   string
+	}{
+		{name: "visa 3", number: "4242424242424242", cvv: "123"},
+		{name: "visa 4", number: "4242424242424242", cvv: "1234", want: gateway.CodeInvalidLength},
+		{name: "amex 4", number: "378282246310005", cvv: "1234"},
+		{name: "amex 3", number: "378282246310005", cvv: "123", want: gateway.CodeInvalidLength},
+		{name: "unknown 3", number: withCheckDigit("1", 16), cvv: "123"},
+		{name: "unknown 4", number: withCheckDigit("1", 16), cvv: "1234"},
+		{name: "unknown 5", number: withCheckDigit("1", 16), cvv: "12345", want: gateway.CodeInvalidLength},
+		{name: "letters", number: "4242424242424242", cvv: "12a", want: gateway.CodeInvalidFormat},
+		{name: "missing", number: "4242424242424242", cvv: "", want: gateway.CodeRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator().Validate(Card{Number: tt.number, Holder: "Jane Doe", Expiry: "12/40", CVV: tt.cvv})
+			if got := fieldCodes(t, err)[FieldCVV]; got != tt.want {
+				t.Fatalf("cvv code = %q, want %q (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidatorReportsEveryField(t *testing.T) {
+	err := NewValidator().Validate(Card{})
+
+	want := map[string]string{
+		FieldNumber: gateway.CodeRequired,
+		FieldHolder: gateway.CodeRequired,
+		FieldExpiry: gateway.CodeRequired,
+		FieldCVV:    gateway.CodeRequired,
+	}
+
+	got := fieldCodes(t, err)
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("%s code = %q, want %q", field, got[field], code)
+		}
+	}
+	if !errors.Is(err, gateway.ErrValidation) {
+		t.Fatalf("Validate(empty) = %v, want ErrValidation", err)
+	}
+}
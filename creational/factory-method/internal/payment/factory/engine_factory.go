@@ -0,0 +1,107 @@
+package factory
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+)
+
+type EngineGatewayFactory struct {
+	provider engine.Provider
+	options  options
+	breaker  *gateway.CircuitBreaker
+	store    engine.TransactionStore
+	mu       sync.Mutex
+}
+
+func NewEngineGatewayFactory(provider engine.Provider, opts ...Option) *EngineGatewayFactory {
+	o := newOptions(opts...)
+
+	return &EngineGatewayFactory{
+		provider: provider,
+		options:  o,
+		breaker:  gateway.NewCircuitBreaker(o.breakerConfig),
+	}
+}
+
+func (f *EngineGatewayFactory) GetPaymentGateway() (gateway.PaymentGateway, error) {
+	store, err := f.transactionStore()
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := engine.NewGateway(
+		f.provider,
+		store,
+		engine.WithAuthorizationTTL(f.options.authorizationTTL),
+		engine.WithClock(f.options.clock),
+		engine.WithCardVault(f.options.cardVault),
+	)
+
+	return gateway, nil
+}
+
+func (f *EngineGatewayFactory) transactionStore() (engine.TransactionStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.store != nil {
+		return f.store, nil
+	}
+
+	store, err := f.newStore()
+	if err != nil {
+		return nil, err
+	}
+	f.store = store
+
+	return store, nil
+}
+
+func (f *EngineGatewayFactory) newStore() (engine.TransactionStore, error) {
+	storeOptions := []engine.StoreOption{
+		engine.WithIdempotencyRetention(f.options.idempotencyRetention),
+		engine.WithFaultInjector(gateway.NewFaultInjector(f.options.faultConfig)),
+		engine.WithRetryPolicy(f.options.retryPolicy),
+		engine.WithCircuitBreaker(f.breaker),
+		engine.WithCommitListener(f.options.commitListener),
+	}
+
+	name := f.provider.Name
+
+	if f.options.sqlDB != nil {
+		return engine.NewSQLTransactionStore(context.Background(), name, f.options.sqlDB, f.options.sqlDialect, storeOptions...)
+	}
+
+	if f.options.storeDir != "" {
+		store, err := engine.NewFileTransactionStore(name, filepath.Join(f.options.storeDir, name), f.options.walOptions, storeOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	}
+
+	return engine.NewInMemoryTransactionStore(name, storeOptions...), nil
+}
+
+func (f *EngineGatewayFactory) CircuitBreakerStats() gateway.BreakerStats {
+	return f.breaker.Stats()
+}
+
+func (f *EngineGatewayFactory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	closer, ok := f.store.(io.Closer)
+	f.store = nil
+	if !ok {
+		return nil
+	}
+
+	return closer.Close()
+}
@@ -0,0 +1,125 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/wal"
+	"factory-method/pkg/money"
+)
+
+func TestEngineGatewayFactoryServesCustomProvider(t *testing.T) {
+	registry := NewDefaultRegistry(WithoutFaults())
+
+	err := registry.Register("acme", NewEngineGatewayFactory(engine.Provider{
+		Name:            "acme",
+		ValidationRules: engine.DefaultValidationRules(),
+		Fee:             engine.PercentageFee(100),
+	}, WithoutFaults()), ProviderMetadata{DisplayName: "Acme"})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	provider, err := registry.Lookup("acme")
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+
+	g, err := provider.Factory.GetPaymentGateway()
+	if err != nil {
+		t.Fatalf("GetPaymentGateway: %v", err)
+	}
+
+	status, err := g.ProcessPayment(context.Background(), gateway.PaymentDetails{
+		Amount:     money.New(10000, "USD"),
+		CardNumber: "4242424242424242",
+		CardHolder: "Jane Doe",
+		ExpiryDate: "12/40",
+		CVV:        "123",
+	})
+	if err != nil {
+		t.Fatalf("ProcessPayment: %v", err)
+	}
+
+	if status.Provider != "acme" || status.Fee.Amount() != 100 {
+		t.Fatalf("payment = provider %q fee %s, want acme with fee 1.00 USD", status.Provider, status.Fee)
+	}
+
+	if _, ok := provider.Factory.(CircuitBreakerReporter); !ok {
+		t.Fatal("engine factory does not report circuit breaker stats")
+	}
+}
+
+func TestRegistryCloseClosesFileStores(t *testing.T) {
+	registry := NewDefaultRegistry(
+		WithoutFaults(),
+		WithoutRetries(),
+		WithFileStore(t.TempDir(), wal.DefaultOptions()),
+	)
+
+	provider, err := registry.Lookup(StripeProviderName)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+
+	g, err := provider.Factory.GetPaymentGateway()
+	if err != nil {
+		t.Fatalf("GetPaymentGateway: %v", err)
+	}
+
+	if err := registry.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	_, err = g.ProcessPayment(context.Background(), gateway.PaymentDetails{
+		Amount:     money.New(1000, "USD"),
+		CardNumber: "4242424242424242",
+		CardHolder: "Jane Doe",
+		ExpiryDate: "12/40",
+		CVV:        "123",
+	})
+	if !errors.Is(err, wal.ErrClosed) {
+		t.Fatalf("ProcessPayment after Close = %v, want wal.ErrClosed", err)
+	}
+}
+
+func TestEngineGatewayFactorySharesOneFileStore(t *testing.T) {
+	registry := NewDefaultRegistry(
+		WithoutFaults(),
+		WithoutRetries(),
+		WithFileStore(t.TempDir(), wal.DefaultOptions()),
+	)
+	t.Cleanup(func() { _ = registry.Close() })
+
+	provider, err := registry.Lookup(StripeProviderName)
+	if err != nil {
+		t.Fatalf("Lookup: %v", err)
+	}
+
+	first, err := provider.Factory.GetPaymentGateway()
+	if err != nil {
+		t.Fatalf("GetPaymentGateway: %v", err)
+	}
+	second, err := provider.Factory.GetPaymentGateway()
+	if err != nil {
+		t.Fatalf("second GetPaymentGateway: %v", err)
+	}
+
+	status, err := first.ProcessPayment(context.Background(), gateway.PaymentDetails{
+		Amount:     money.New(1000, "USD"),
+		CardNumber: "4242424242424242",
+		CardHolder: "Jane Doe",
+		ExpiryDate: "12/40",
+		CVV:        "123",
+	})
+	if err != nil {
+		t.Fatalf("ProcessPayment: %v", err)
+	}
+
+	if _, err := second.GetStatus(context.Background(), status.TransactionID); err != nil {
+		t.Fatalf("second gateway GetStatus: %v", err)
+	}
+}
@@ -3,5 +3,9 @@ package factory
 import "factory-method/internal/payment/gateway"
 
 type PaymentGatewayFactory interface {
-	GetPaymentGateway() gateway.PaymentGateway
+	GetPaymentGateway() (gateway.PaymentGateway, error)
+}
+
+type CircuitBreakerReporter interface {
+	CircuitBreakerStats() gateway.BreakerStats
 }
@@ -0,0 +1,118 @@
+package factory
+
+import (
+	"database/sql"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/sqlstore"
+	"factory-method/internal/payment/gateway/wal"
+)
+
+type options struct {
+	idempotencyRetention time.Duration
+	authorizationTTL     time.Duration
+	clock                func() time.Time
+	cardVault            engine.CardVault
+	faultConfig          gateway.FaultConfig
+	retryPolicy          engine.RetryPolicy
+	breakerConfig        gateway.BreakerConfig
+	commitListener       gateway.CommitListener
+	storeDir             string
+	walOptions           wal.Options
+	sqlDB                *sql.DB
+	sqlDialect           sqlstore.Dialect
+}
+
+type Option func(*options)
+
+func WithIdempotencyRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.idempotencyRetention = retention
+	}
+}
+
+func WithAuthorizationTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.authorizationTTL = ttl
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.clock = now
+	}
+}
+
+func WithCardVault(vault engine.CardVault) Option {
+	return func(o *options) {
+		o.cardVault = vault
+	}
+}
+
+func WithFaultConfig(config gateway.FaultConfig) Option {
+	return func(o *options) {
+		o.faultConfig = config
+	}
+}
+
+func WithoutFaults() Option {
+	return WithFaultConfig(gateway.DisabledFaultConfig())
+}
+
+func WithRetryPolicy(policy engine.RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = policy
+	}
+}
+
+func WithoutRetries() Option {
+	return WithRetryPolicy(engine.NoRetryPolicy())
+}
+
+func WithCircuitBreaker(config gateway.BreakerConfig) Option {
+	return func(o *options) {
+		o.breakerConfig = config
+	}
+}
+
+func WithoutCircuitBreaker() Option {
+	return WithCircuitBreaker(gateway.DisabledBreakerConfig())
+}
+
+func WithCommitListener(listener gateway.CommitListener) Option {
+	return func(o *options) {
+		o.commitListener = listener
+	}
+}
+
+func WithFileStore(dir string, walOptions wal.Options) Option {
+	return func(o *options) {
+		o.storeDir = dir
+		o.walOptions = walOptions
+	}
+}
+
+func WithSQLStore(db *sql.DB, dialect sqlstore.Dialect) Option {
+	return func(o *options) {
+		o.sqlDB = db
+		o.sqlDialect = dialect
+	}
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		idempotencyRetention: gateway.DefaultIdempotencyRetention,
+		authorizationTTL:     gateway.DefaultAuthorizationTTL,
+		faultConfig:          gateway.DefaultFaultConfig(),
+		retryPolicy:          engine.DefaultRetryPolicy(),
+		breakerConfig:        gateway.DefaultBreakerConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
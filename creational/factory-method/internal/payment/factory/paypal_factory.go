@@ -1,22 +1,24 @@
 package factory
 
-import (
-	"factory-method/internal/payment/gateway"
-	"factory-method/internal/payment/gateway/paypal"
-)
+import "factory-method/internal/payment/gateway/paypal"
 
-type PaypalGatewayFactory struct{}
-
-func NewPaypalGatewayFactory() *PaypalGatewayFactory {
-	return &PaypalGatewayFactory{}
+type PaypalGatewayFactory struct {
+	*EngineGatewayFactory
 }
 
-func (*PaypalGatewayFactory) GetPaymentGateway() gateway.PaymentGateway {
-	validator := paypal.NewDefaultValidator()
-	authenticator := paypal.NewSimpleCardAuthenticator()
-	store := paypal.NewTransactionStore()
-
-	gateway := paypal.NewPaypalPaymentGateway(store, validator, authenticator)
+func NewPaypalGatewayFactory(opts ...Option) *PaypalGatewayFactory {
+	return &PaypalGatewayFactory{
+		EngineGatewayFactory: NewEngineGatewayFactory(paypal.Provider(), opts...),
+	}
+}
 
-	return gateway
+func PaypalMetadata() ProviderMetadata {
+	return ProviderMetadata{
+		DisplayName: "PayPal",
+		SupportedCurrencies: []string{
+			"AUD", "CAD", "CHF", "CZK", "DKK", "EUR", "GBP", "HKD",
+			"HUF", "JPY", "MXN", "NOK", "NZD", "PLN", "SEK", "SGD", "USD",
+		},
+		Fee: paypal.Provider().Fee,
+	}
 }
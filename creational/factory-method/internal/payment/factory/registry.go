@@ -0,0 +1,143 @@
+package factory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/paypal"
+	"factory-method/internal/payment/gateway/stripe"
+	"factory-method/pkg/money"
+)
+
+const (
+	StripeProviderName = stripe.Name
+	PaypalProviderName = paypal.Name
+)
+
+var (
+	ErrProviderAlreadyRegistered = errors.New("payment provider already registered")
+	ErrProviderNotRegistered     = errors.New("payment provider not registered")
+	ErrInvalidProvider           = errors.New("invalid payment provider")
+)
+
+type ProviderMetadata struct {
+	DisplayName         string
+	SupportedCurrencies []string
+	Fee                 engine.FeeRule
+}
+
+func (m ProviderMetadata) SupportsCurrency(currency string) bool {
+	if len(m.SupportedCurrencies) == 0 {
+		return true
+	}
+	return slices.Contains(m.SupportedCurrencies, strings.ToUpper(currency))
+}
+
+func (m ProviderMetadata) EstimateFee(amount money.Money) money.Money {
+	if m.Fee == nil {
+		return engine.NoFee(amount)
+	}
+	return m.Fee(amount)
+}
+
+type Provider struct {
+	Name     string
+	Metadata ProviderMetadata
+	Factory  PaymentGatewayFactory
+}
+
+type Registry struct {
+	providers map[string]Provider
+	mu        sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]Provider),
+	}
+}
+
+func NewDefaultRegistry(opts ...Option) *Registry {
+	registry := NewRegistry()
+
+	_ = registry.Register(StripeProviderName, NewStripeGatewayFactory(opts...), StripeMetadata())
+	_ = registry.Register(PaypalProviderName, NewPaypalGatewayFactory(opts...), PaypalMetadata())
+
+	return registry
+}
+
+func (r *Registry) Register(name string, factory PaymentGatewayFactory, metadata ProviderMetadata) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidProvider)
+	}
+	if factory == nil {
+		return fmt.Errorf("%w: %s has no factory", ErrInvalidProvider, name)
+	}
+
+	currencies := make([]string, 0, len(metadata.SupportedCurrencies))
+	for _, c := range metadata.SupportedCurrencies {
+		currencies = append(currencies, strings.ToUpper(c))
+	}
+	metadata.SupportedCurrencies = currencies
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.providers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrProviderAlreadyRegistered, name)
+	}
+
+	r.providers[name] = Provider{
+		Name:     name,
+		Metadata: metadata,
+		Factory:  factory,
+	}
+
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return Provider{}, fmt.Errorf("%w: %s", ErrProviderNotRegistered, name)
+	}
+
+	return provider, nil
+}
+
+func (r *Registry) List() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	providers := make([]Provider, 0, len(r.providers))
+	for _, provider := range r.providers {
+		providers = append(providers, provider)
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+
+	return providers
+}
+
+func (r *Registry) Close() error {
+	var errs []error
+	for _, provider := range r.List() {
+		if c, ok := provider.Factory.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
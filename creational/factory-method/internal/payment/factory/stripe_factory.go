@@ -1,22 +1,24 @@
 package factory
 
-import (
-	"factory-method/internal/payment/gateway"
-	"factory-method/internal/payment/gateway/stripe"
-)
+import "factory-method/internal/payment/gateway/stripe"
 
-type StripeGatewayFactory struct{}
-
-func NewStripeGatewayFactory() *StripeGatewayFactory {
-	return &StripeGatewayFactory{}
+type StripeGatewayFactory struct {
+	*EngineGatewayFactory
 }
 
-func (*StripeGatewayFactory) GetPaymentGateway() gateway.PaymentGateway {
-	validator := stripe.NewDefaultValidator()
-	authenticator := stripe.NewSimpleCardAuthenticator()
-	store := stripe.NewTransactionStore()
-
-	gateway := stripe.NewStripePaymentGateway(store, validator, authenticator)
+func NewStripeGatewayFactory(opts ...Option) *StripeGatewayFactory {
+	return &StripeGatewayFactory{
+		EngineGatewayFactory: NewEngineGatewayFactory(stripe.Provider(), opts...),
+	}
+}
 
-	return gateway
+func StripeMetadata() ProviderMetadata {
+	return ProviderMetadata{
+		DisplayName: "Stripe",
+		SupportedCurrencies: []string{
+			"AUD", "BRL", "CAD", "CHF", "DKK", "EUR", "GBP", "HKD",
+			"JPY", "MXN", "NOK", "NZD", "PLN", "SEK", "SGD", "USD",
+		},
+		Fee: stripe.Provider().Fee,
+	}
 }
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrProviderUnavailable = errors.New("provider is temporarily unavailable")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+type BreakerConfig struct {
+	Disabled            bool
+	FailureThreshold    int
+	SuccessThreshold    int
+	HalfOpenMaxRequests int
+	Cooldown            time.Duration
+}
+
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold:    5,
+		SuccessThreshold:    2,
+		HalfOpenMaxRequests: 1,
+		Cooldown:            30 * time.Second,
+	}
+}
+
+func DisabledBreakerConfig() BreakerConfig {
+	return BreakerConfig{Disabled: true}
+}
+
+type BreakerStats struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	TotalFailures       int64
+	Rejected            int64
+	OpenedAt            time.Time
+	RetryAt             time.Time
+}
+
+type Admission struct {
+	probe      bool
+	generation uint64
+}
+
+type CircuitBreaker struct {
+	config     BreakerConfig
+	now        func() time.Time
+	state      BreakerState
+	generation uint64
+	failures   int
+	successes  int
+	inFlight   int
+	stats      BreakerStats
+	mu         sync.Mutex
+}
+
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	defaults := DefaultBreakerConfig()
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaults.FailureThreshold
+	}
+	if config.SuccessThreshold <= 0 {
+		config.SuccessThreshold = defaults.SuccessThreshold
+	}
+	if config.HalfOpenMaxRequests <= 0 {
+		config.HalfOpenMaxRequests = defaults.HalfOpenMaxRequests
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaults.Cooldown
+	}
+
+	return &CircuitBreaker{
+		config: config,
+		now:    func() time.Time { return time.Now().UTC() },
+		state:  BreakerClosed,
+	}
+}
+
+func (b *CircuitBreaker) Allow() (Admission, bool) {
+	if b == nil || b.config.Disabled {
+		return Admission{}, true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && !b.now().Before(b.stats.RetryAt) {
+		b.transition(BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		b.stats.Rejected++
+		return Admission{}, false
+	case BreakerHalfOpen:
+		if b.inFlight >= b.config.HalfOpenMaxRequests {
+			b.stats.Rejected++
+			return Admission{}, false
+		}
+		b.inFlight++
+		return Admission{probe: true, generation: b.generation}, true
+	}
+
+	return Admission{generation: b.generation}, true
+}
+
+func (b *CircuitBreaker) Record(admission Admission, failed bool) {
+	if b == nil || b.config.Disabled {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		b.stats.TotalFailures++
+	}
+
+	if admission.generation != b.generation {
+		return
+	}
+
+	if admission.probe {
+		b.inFlight--
+	}
+
+	if failed {
+		b.failures++
+		b.successes = 0
+
+		if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+			b.transition(BreakerOpen)
+		}
+		return
+	}
+
+	b.failures = 0
+
+	if b.state == BreakerHalfOpen {
+		b.successes++
+		if b.successes >= b.config.SuccessThreshold {
+			b.transition(BreakerClosed)
+		}
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	return b.Stats().State
+}
+
+func (b *CircuitBreaker) Stats() BreakerStats {
+	if b == nil || b.config.Disabled {
+		return BreakerStats{State: BreakerClosed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.stats
+	stats.State = b.state
+	stats.ConsecutiveFailures = b.failures
+
+	if b.state == BreakerOpen && !b.now().Before(stats.RetryAt) {
+		stats.State = BreakerHalfOpen
+	}
+
+	return stats
+}
+
+func (b *CircuitBreaker) transition(to BreakerState) {
+	b.state = to
+	b.generation++
+	b.successes = 0
+	b.inFlight = 0
+
+	switch to {
+	case BreakerOpen:
+		b.stats.OpenedAt = b.now()
+		b.stats.RetryAt = b.stats.OpenedAt.Add(b.config.Cooldown)
+	case BreakerClosed:
+		b.failures = 0
+		b.stats.OpenedAt = time.Time{}
+		b.stats.RetryAt = time.Time{}
+	}
+}
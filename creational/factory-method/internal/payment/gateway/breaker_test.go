@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestBreaker(config BreakerConfig) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b := NewCircuitBreaker(config)
+	b.now = func() time.Time { return now }
+
+	return b, &now
+}
+
+func mustAllow(t *testing.T, b *CircuitBreaker) Admission {
+	t.Helper()
+
+	admission, ok := b.Allow()
+	if !ok {
+		t.Fatalf("Allow rejected call in state %s", b.State())
+	}
+
+	return admission
+}
+
+func TestBreakerIgnoresClosedCallsWhenCountingProbes(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{
+		FailureThreshold:    1,
+		SuccessThreshold:    1,
+		HalfOpenMaxRequests: 1,
+		Cooldown:            time.Second,
+	})
+
+	tripping := mustAllow(t, b)
+	slow := mustAllow(t, b)
+
+	b.Record(tripping, true)
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("state after failure = %s, want %s", state, BreakerOpen)
+	}
+
+	*now = now.Add(time.Second)
+
+	probe := mustAllow(t, b)
+	if _, ok := b.Allow(); ok {
+		t.Fatal("second half-open call admitted, want probe limit of 1")
+	}
+
+	b.Record(slow, false)
+
+	if _, ok := b.Allow(); ok {
+		t.Fatal("closed-state call freed a half-open probe slot")
+	}
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Fatalf("closed-state success moved breaker to %s, want %s", state, BreakerHalfOpen)
+	}
+	if b.inFlight != 1 {
+		t.Fatalf("inFlight = %d, want 1", b.inFlight)
+	}
+
+	b.Record(probe, false)
+	if state := b.State(); state != BreakerClosed {
+		t.Fatalf("state after successful probe = %s, want %s", state, BreakerClosed)
+	}
+	if b.inFlight != 0 {
+		t.Fatalf("inFlight after probe = %d, want 0", b.inFlight)
+	}
+}
+
+func TestBreakerInFlightNeverNegative(t *testing.T) {
+	b, _ := newTestBreaker(BreakerConfig{FailureThreshold: 3, Cooldown: time.Second})
+
+	for range 10 {
+		b.Record(mustAllow(t, b), false)
+	}
+
+	if b.inFlight != 0 {
+		t.Fatalf("inFlight = %d, want 0", b.inFlight)
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Second})
+
+	b.Record(mustAllow(t, b), true)
+	*now = now.Add(time.Second)
+
+	b.Record(mustAllow(t, b), true)
+
+	if state := b.State(); state != BreakerOpen {
+		t.Fatalf("state after failed probe = %s, want %s", state, BreakerOpen)
+	}
+	if _, ok := b.Allow(); ok {
+		t.Fatal("Allow admitted call while open")
+	}
+}
+
+func TestBreakerLateFailureKeepsRetryAt(t *testing.T) {
+	b, now := newTestBreaker(BreakerConfig{FailureThreshold: 1, Cooldown: time.Second})
+
+	tripping := mustAllow(t, b)
+	late := mustAllow(t, b)
+
+	b.Record(tripping, true)
+	retryAt := b.Stats().RetryAt
+
+	*now = now.Add(500 * time.Millisecond)
+	b.Record(late, true)
+
+	stats := b.Stats()
+	if !stats.RetryAt.Equal(retryAt) {
+		t.Fatalf("RetryAt after late failure = %s, want %s", stats.RetryAt, retryAt)
+	}
+	if stats.ConsecutiveFailures != 1 || stats.TotalFailures != 2 {
+		t.Fatalf("consecutive = %d, total = %d, want 1 and 2", stats.ConsecutiveFailures, stats.TotalFailures)
+	}
+}
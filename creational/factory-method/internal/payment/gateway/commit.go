@@ -0,0 +1,16 @@
+package gateway
+
+import "time"
+
+type Commit struct {
+	TransactionID  string
+	Transaction    *TransactionStatus
+	Events         []TransactionEvent
+	IdempotencyKey string
+	Fingerprint    string
+	CommittedAt    time.Time
+}
+
+type CommitHook func(Commit) error
+
+type CommitListener func(Commit)
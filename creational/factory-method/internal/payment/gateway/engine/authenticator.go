@@ -0,0 +1,21 @@
+package engine
+
+import "factory-method/internal/payment/card"
+
+type CardAllowlist struct {
+	validCards map[string]bool
+}
+
+func NewCardAllowlist(cards ...string) *CardAllowlist {
+	validCards := make(map[string]bool, len(cards))
+	for _, number := range cards {
+		validCards[card.Normalize(number)] = true
+	}
+
+	return &CardAllowlist{validCards: validCards}
+}
+
+func (a *CardAllowlist) Authenticate(number string) bool {
+	_, valid := a.validCards[card.Normalize(number)]
+	return valid
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/wal"
+)
+
+type FileTransactionStore struct {
+	*InMemoryTransactionStore
+	log *wal.Log
+}
+
+func NewFileTransactionStore(provider string, dir string, walOptions wal.Options, opts ...StoreOption) (*FileTransactionStore, error) {
+	s := &FileTransactionStore{}
+
+	hook := func(c gateway.Commit) error {
+		return s.log.Append(c)
+	}
+
+	s.InMemoryTransactionStore = NewInMemoryTransactionStore(provider, append(opts, WithCommitHook(hook))...)
+
+	log, err := wal.Open(dir, walOptions, s.InMemoryTransactionStore)
+	if err != nil {
+		return nil, s.errorf("open transaction log: %w", err)
+	}
+
+	s.log = log
+
+	return s, nil
+}
+
+func (s *FileTransactionStore) Compact() error {
+	return s.log.Compact()
+}
+
+func (s *FileTransactionStore) Close() error {
+	return s.log.Close()
+}
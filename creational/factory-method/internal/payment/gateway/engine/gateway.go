@@ -0,0 +1,457 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"factory-method/internal/payment/card"
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+	"slices"
+	"time"
+)
+
+const maxVersionConflictRetries = 3
+
+type Validator interface {
+	Validate(gateway.PaymentDetails) error
+}
+
+type SaveTransaction struct {
+	TransactionID          string
+	IdempotencyKey         string
+	Fingerprint            string
+	Amount                 money.Money
+	Fee                    money.Money
+	Authorize              bool
+	AuthorizationExpiresAt time.Time
+	MerchantReference      string
+	Metadata               gateway.Metadata
+}
+
+type CaptureTransaction struct {
+	Amount money.Money
+}
+
+type RefundTransaction struct {
+	Amount            money.Money
+	Reason            string
+	MerchantReference string
+	Metadata          gateway.Metadata
+}
+
+type UpdateTransaction struct {
+	IdempotencyKey  string
+	Fingerprint     string
+	TransactionID   string
+	ExpectedVersion int64
+	Status          gateway.TransactionStatusType
+	Reason          string
+	Capture         *CaptureTransaction
+	Refund          *RefundTransaction
+}
+
+type TransactionStore interface {
+	Save(ctx context.Context, saveTransaction *SaveTransaction) (*gateway.TransactionStatus, error)
+	Get(ctx context.Context, id string) (*gateway.TransactionStatus, error)
+	Update(ctx context.Context, updateTransaction UpdateTransaction) (*gateway.TransactionStatus, error)
+	FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, error)
+	GetHistory(ctx context.Context, id string) ([]gateway.TransactionEvent, error)
+	List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error)
+}
+
+type Authenticator interface {
+	Authenticate(card string) bool
+}
+
+type CardVault interface {
+	Detokenize(ctx context.Context, token string) (card.Card, error)
+}
+
+type Gateway struct {
+	provider         Provider
+	store            TransactionStore
+	vault            CardVault
+	validator        Validator
+	authenticator    Authenticator
+	authorizationTTL time.Duration
+	now              func() time.Time
+}
+
+type GatewayOption func(*Gateway)
+
+func WithAuthorizationTTL(ttl time.Duration) GatewayOption {
+	return func(g *Gateway) {
+		if ttl > 0 {
+			g.authorizationTTL = ttl
+		}
+	}
+}
+
+func WithClock(now func() time.Time) GatewayOption {
+	return func(g *Gateway) {
+		if now != nil {
+			g.now = now
+		}
+	}
+}
+
+func WithCardVault(vault CardVault) GatewayOption {
+	return func(g *Gateway) {
+		g.vault = vault
+	}
+}
+
+func NewGateway(provider Provider, store TransactionStore, opts ...GatewayOption) *Gateway {
+	g := &Gateway{
+		provider:         provider,
+		store:            store,
+		authenticator:    provider.Authenticator,
+		authorizationTTL: gateway.DefaultAuthorizationTTL,
+		now:              func() time.Time { return time.Now().UTC() },
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	cardValidator := card.NewValidator(
+		card.WithAcceptedBrands(provider.AcceptedBrands...),
+		card.WithClock(g.now),
+	)
+
+	rules := append(slices.Clone(provider.ValidationRules), MetadataLimits, CardRule(cardValidator))
+	g.validator = NewValidator(rules...)
+
+	return g
+}
+
+func (g *Gateway) ProcessPayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
+	return g.createTransaction(ctx, details, gateway.PaymentFingerprint(details), false)
+}
+
+func (g *Gateway) Authorize(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
+	return g.createTransaction(ctx, details, gateway.AuthorizationFingerprint(details), true)
+}
+
+func (g *Gateway) Capture(ctx context.Context, details gateway.CaptureDetails) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	fingerprint := gateway.CaptureFingerprint(details)
+
+	if replayed, ok, err := g.replay(ctx, details.IdempotencyKey, fingerprint); err != nil || ok {
+		return replayed, err
+	}
+
+	return g.updateLatest(ctx, details.TransactionID, func(transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+		return g.prepareCapture(details, fingerprint, transaction)
+	})
+}
+
+func (g *Gateway) Void(ctx context.Context, details gateway.VoidDetails) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return g.updateLatest(ctx, details.TransactionID, func(transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+		return g.prepareVoid(details, transaction)
+	})
+}
+
+func (g *Gateway) createTransaction(ctx context.Context, details gateway.PaymentDetails, fingerprint string, authorize bool) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if replayed, ok, err := g.replay(ctx, details.IdempotencyKey, fingerprint); err != nil || ok {
+		return replayed, err
+	}
+
+	resolved, err := g.resolveCard(ctx, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := g.validator.Validate(resolved); err != nil {
+		return nil, err
+	}
+
+	if g.authenticator != nil && !g.authenticator.Authenticate(resolved.CardNumber) {
+		return nil, g.provider.decline(gateway.DeclineAuthenticationFailed, "card could not be authenticated")
+	}
+
+	saveTransaction := &SaveTransaction{
+		IdempotencyKey:    details.IdempotencyKey,
+		Fingerprint:       fingerprint,
+		Amount:            details.Amount,
+		Fee:               g.provider.fee(details.Amount),
+		Authorize:         authorize,
+		MerchantReference: details.MerchantReference,
+		Metadata:          details.Metadata.Clone(),
+	}
+
+	if authorize {
+		saveTransaction.AuthorizationExpiresAt = g.now().Add(g.authorizationTTL)
+	}
+
+	savedTransaction, err := g.store.Save(ctx, saveTransaction)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return savedTransaction, nil
+}
+
+func (g *Gateway) Refund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err := validateReferences(details.MerchantReference, details.Metadata); err != nil {
+		return nil, err
+	}
+
+	fingerprint := gateway.RefundFingerprint(details)
+
+	if replayed, ok, err := g.replay(ctx, details.IdempotencyKey, fingerprint); err != nil || ok {
+		return replayed, err
+	}
+
+	return g.updateLatest(ctx, details.TransactionID, func(transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+		return g.prepareRefund(details, fingerprint, transaction)
+	})
+}
+
+func (g *Gateway) prepareCapture(details gateway.CaptureDetails, fingerprint string, transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+	if transaction.Status != gateway.StatusAuthorized {
+		return nil, g.provider.fail(gateway.KindConflict, "cannot capture transaction in status %q", transaction.Status)
+	}
+
+	if transaction.AuthorizationExpired(g.now()) {
+		return nil, g.provider.fail(gateway.KindConflict, "authorization has expired")
+	}
+
+	amount := details.Amount
+	if amount.IsZero() {
+		amount = transaction.Amount
+	}
+
+	if !amount.IsPositive() {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "capture amount must be greater than zero")
+	}
+
+	cmp, err := amount.Cmp(transaction.Amount)
+	if err != nil {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "capture currency does not match authorization: %w", err)
+	}
+
+	if cmp > 0 {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "capture amount %s exceeds authorized amount %s", amount, transaction.Amount)
+	}
+
+	return &UpdateTransaction{
+		IdempotencyKey: details.IdempotencyKey,
+		Fingerprint:    fingerprint,
+		TransactionID:  details.TransactionID,
+		Status:         gateway.StatusCompleted,
+		Capture: &CaptureTransaction{
+			Amount: amount,
+		},
+	}, nil
+}
+
+func (g *Gateway) prepareVoid(details gateway.VoidDetails, transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+	if transaction.Status == gateway.StatusVoided {
+		return nil, nil
+	}
+
+	if transaction.Status != gateway.StatusAuthorized {
+		return nil, g.provider.fail(gateway.KindConflict, "cannot void transaction in status %q", transaction.Status)
+	}
+
+	return &UpdateTransaction{
+		TransactionID: details.TransactionID,
+		Status:        gateway.StatusVoided,
+		Reason:        details.Reason,
+	}, nil
+}
+
+func (g *Gateway) prepareRefund(details gateway.RefundDetails, fingerprint string, transaction *gateway.TransactionStatus) (*UpdateTransaction, error) {
+	if !transaction.IsRefundable() {
+		return nil, g.provider.fail(gateway.KindConflict, "cannot refund transaction in status %q", transaction.Status)
+	}
+
+	if !details.Amount.IsPositive() {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "refund amount must be greater than zero")
+	}
+
+	refundable, err := transaction.RefundableAmount()
+	if err != nil {
+		return nil, g.provider.errorf("failed to compute refundable amount: %w", err)
+	}
+
+	cmp, err := details.Amount.Cmp(refundable)
+	if err != nil {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "refund currency does not match transaction: %w", err)
+	}
+
+	if cmp > 0 {
+		return nil, g.provider.fail(gateway.KindInvalidRequest, "refund amount %s exceeds remaining refundable amount %s", details.Amount, refundable)
+	}
+
+	status := gateway.StatusPartiallyRefunded
+	if cmp == 0 {
+		status = gateway.StatusRefund
+	}
+
+	return &UpdateTransaction{
+		IdempotencyKey: details.IdempotencyKey,
+		Fingerprint:    fingerprint,
+		TransactionID:  details.TransactionID,
+		Status:         status,
+		Refund: &RefundTransaction{
+			Amount:            details.Amount,
+			Reason:            details.Reason,
+			MerchantReference: details.MerchantReference,
+			Metadata:          details.Metadata.Clone(),
+		},
+	}, nil
+}
+
+func (g *Gateway) updateLatest(ctx context.Context, id string, prepare func(*gateway.TransactionStatus) (*UpdateTransaction, error)) (*gateway.TransactionStatus, error) {
+	for attempt := 1; ; attempt++ {
+		transaction, err := g.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		updateTransaction, err := prepare(transaction)
+		if err != nil {
+			return nil, err
+		}
+
+		if updateTransaction == nil {
+			return transaction, nil
+		}
+
+		updateTransaction.ExpectedVersion = transaction.Version
+
+		updatedTransaction, err := g.store.Update(ctx, *updateTransaction)
+		if errors.Is(err, gateway.ErrVersionConflict) && attempt < maxVersionConflictRetries {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return updatedTransaction, nil
+	}
+}
+
+func (g *Gateway) GetStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	transaction, err := g.store.Get(ctx, transactionID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (g *Gateway) GetHistory(ctx context.Context, transactionID string) ([]gateway.TransactionEvent, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	history, err := g.store.GetHistory(ctx, transactionID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (g *Gateway) List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	page, err := g.store.List(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (g *Gateway) FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	transaction, err := g.store.FindByIdempotencyKey(ctx, key, fingerprint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (g *Gateway) resolveCard(ctx context.Context, details gateway.PaymentDetails) (gateway.PaymentDetails, error) {
+	if details.CardToken == "" {
+		return details, nil
+	}
+
+	if g.vault == nil {
+		return details, g.provider.fail(gateway.KindInvalidRequest, "card tokens are not supported without a vault")
+	}
+
+	c, err := g.vault.Detokenize(ctx, details.CardToken)
+	if err != nil {
+		if ctx.Err() != nil {
+			return details, ctx.Err()
+		}
+
+		errs := &gateway.ValidationError{}
+		errs.Add("card_token", gateway.CodeInvalid, "card token is unknown or cannot be resolved")
+		return details, errs
+	}
+
+	details.CardNumber = c.Number
+	if details.CardHolder == "" {
+		details.CardHolder = c.Holder
+	}
+	if details.ExpiryDate == "" {
+		details.ExpiryDate = c.Expiry
+	}
+
+	return details, nil
+}
+
+func (g *Gateway) replay(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, bool, error) {
+	if key == "" {
+		return nil, false, nil
+	}
+
+	transaction, err := g.store.FindByIdempotencyKey(ctx, key, fingerprint)
+
+	if errors.Is(err, gateway.ErrIdempotencyKeyNotFound) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return transaction, true, nil
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sort"
+
+	"factory-method/internal/payment/gateway"
+)
+
+type indexEntry struct {
+	key int64
+	id  string
+}
+
+func (e indexEntry) compare(key int64, id string) int {
+	return cmp.Or(cmp.Compare(e.key, key), cmp.Compare(e.id, id))
+}
+
+type sortedIndex []indexEntry
+
+func (x sortedIndex) search(key int64, id string) int {
+	return sort.Search(len(x), func(i int) bool {
+		return x[i].compare(key, id) >= 0
+	})
+}
+
+func (x *sortedIndex) insert(e indexEntry) {
+	i := x.search(e.key, e.id)
+	*x = slices.Insert(*x, i, e)
+}
+
+func (x *sortedIndex) remove(e indexEntry) {
+	i := x.search(e.key, e.id)
+	if i < len(*x) && (*x)[i] == e {
+		*x = slices.Delete(*x, i, i+1)
+	}
+}
+
+type idSet map[string]struct{}
+
+type transactionIndex struct {
+	byCreatedAt sortedIndex
+	byAmount    sortedIndex
+	byStatus    map[gateway.TransactionStatusType]idSet
+	byCurrency  map[string]idSet
+	byReference map[string]idSet
+}
+
+func newTransactionIndex() *transactionIndex {
+	return &transactionIndex{
+		byStatus:    make(map[gateway.TransactionStatusType]idSet),
+		byCurrency:  make(map[string]idSet),
+		byReference: make(map[string]idSet),
+	}
+}
+
+func (x *transactionIndex) add(t *gateway.TransactionStatus) {
+	x.byCreatedAt.insert(indexEntry{key: t.CreatedAt.UnixNano(), id: t.TransactionID})
+	x.byAmount.insert(indexEntry{key: t.Amount.Amount(), id: t.TransactionID})
+	addToSet(x.byStatus, t.Status, t.TransactionID)
+	addToSet(x.byCurrency, t.Amount.Currency(), t.TransactionID)
+	if t.MerchantReference != "" {
+		addToSet(x.byReference, t.MerchantReference, t.TransactionID)
+	}
+}
+
+func (x *transactionIndex) remove(t *gateway.TransactionStatus) {
+	x.byCreatedAt.remove(indexEntry{key: t.CreatedAt.UnixNano(), id: t.TransactionID})
+	x.byAmount.remove(indexEntry{key: t.Amount.Amount(), id: t.TransactionID})
+	removeFromSet(x.byStatus, t.Status, t.TransactionID)
+	removeFromSet(x.byCurrency, t.Amount.Currency(), t.TransactionID)
+	if t.MerchantReference != "" {
+		removeFromSet(x.byReference, t.MerchantReference, t.TransactionID)
+	}
+}
+
+func (x *transactionIndex) scan(q gateway.ListQuery, transactions map[string]*gateway.TransactionStatus, visit func(*gateway.TransactionStatus) bool) {
+	ordered := x.byCreatedAt
+	if q.SortBy == gateway.SortByAmount {
+		ordered = x.byAmount
+	}
+
+	lo, hi := x.bounds(ordered, q)
+
+	if candidates, ok := x.candidates(q, hi-lo); ok {
+		matched := make([]*gateway.TransactionStatus, 0, len(candidates))
+		for id := range candidates {
+			if t := transactions[id]; q.Matches(t) {
+				matched = append(matched, t)
+			}
+		}
+
+		slices.SortFunc(matched, q.Compare)
+
+		for _, t := range matched {
+			if !visit(t) {
+				return
+			}
+		}
+		return
+	}
+
+	for i := range hi - lo {
+		position := lo + i
+		if q.Descending() {
+			position = hi - 1 - i
+		}
+
+		if t := transactions[ordered[position].id]; q.Matches(t) && !visit(t) {
+			return
+		}
+	}
+}
+
+func (x *transactionIndex) bounds(ordered sortedIndex, q gateway.ListQuery) (int, int) {
+	minKey, maxKey := q.KeyRange()
+
+	lo := ordered.search(minKey, "")
+	hi := len(ordered)
+	if maxKey < math.MaxInt64 {
+		hi = ordered.search(maxKey+1, "")
+	}
+
+	if key, id, ok := q.Position(); ok {
+		position := ordered.search(key, id)
+		if q.Descending() {
+			hi = min(hi, position)
+		} else {
+			if position < len(ordered) && ordered[position].compare(key, id) == 0 {
+				position++
+			}
+			lo = max(lo, position)
+		}
+	}
+
+	return lo, max(lo, hi)
+}
+
+func (x *transactionIndex) candidates(q gateway.ListQuery, scanned int) (idSet, bool) {
+	var (
+		best  idSet
+		found bool
+	)
+
+	if len(q.Filter.Statuses) > 0 {
+		best = make(idSet)
+		for _, status := range q.Filter.Statuses {
+			for id := range x.byStatus[status] {
+				best[id] = struct{}{}
+			}
+		}
+		found = true
+	}
+
+	if q.Filter.Currency != "" {
+		if currency := x.byCurrency[q.Filter.Currency]; !found || len(currency) < len(best) {
+			best = currency
+			found = true
+		}
+	}
+
+	if q.Filter.MerchantReference != "" {
+		if reference := x.byReference[q.Filter.MerchantReference]; !found || len(reference) < len(best) {
+			best = reference
+			found = true
+		}
+	}
+
+	return best, found && len(best) < scanned
+}
+
+func addToSet[K comparable](sets map[K]idSet, key K, id string) {
+	set, ok := sets[key]
+	if !ok {
+		set = make(idSet)
+		sets[key] = set
+	}
+	set[id] = struct{}{}
+}
+
+func removeFromSet[K comparable](sets map[K]idSet, key K, id string) {
+	delete(sets[key], id)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+func newTestIndex() (*transactionIndex, map[string]*gateway.TransactionStatus) {
+	x := newTransactionIndex()
+	transactions := make(map[string]*gateway.TransactionStatus)
+
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []gateway.TransactionStatusType{gateway.StatusCompleted, gateway.StatusFailed, gateway.StatusRefund}
+	currencies := []string{"USD", "EUR"}
+
+	for i := range 24 {
+		t := &gateway.TransactionStatus{
+			TransactionID:     fmt.Sprintf("txn-%02d", i),
+			Status:            statuses[i%len(statuses)],
+			Amount:            money.New(int64(100*(i%7)), currencies[i%len(currencies)]),
+			MerchantReference: fmt.Sprintf("order-%d", i%5),
+			CreatedAt:         createdAt.Add(time.Duration(i/2) * time.Minute),
+		}
+
+		transactions[t.TransactionID] = t
+		x.add(t)
+	}
+
+	return x, transactions
+}
+
+func scanIDs(x *transactionIndex, transactions map[string]*gateway.TransactionStatus, q gateway.ListQuery) []string {
+	var ids []string
+	x.scan(q, transactions, func(t *gateway.TransactionStatus) bool {
+		ids = append(ids, t.TransactionID)
+		return true
+	})
+	return ids
+}
+
+func linearIDs(transactions map[string]*gateway.TransactionStatus, q gateway.ListQuery) []string {
+	var matched []*gateway.TransactionStatus
+	for _, t := range transactions {
+		if q.Matches(t) {
+			matched = append(matched, t)
+		}
+	}
+	slices.SortFunc(matched, q.Compare)
+
+	var ids []string
+	for _, t := range matched {
+		ids = append(ids, t.TransactionID)
+	}
+	return ids
+}
+
+func lookup(transactions map[string]*gateway.TransactionStatus, ids []string) []*gateway.TransactionStatus {
+	found := make([]*gateway.TransactionStatus, 0, len(ids))
+	for _, id := range ids {
+		found = append(found, transactions[id])
+	}
+	return found
+}
+
+func TestIndexScanMatchesLinearScan(t *testing.T) {
+	x, transactions := newTestIndex()
+
+	minAmount, maxAmount := int64(200), int64(400)
+	start := time.Date(2025, 1, 1, 0, 3, 0, 0, time.UTC)
+
+	filters := map[string]gateway.ListFilter{
+		"none":             {},
+		"status":           {Statuses: []gateway.TransactionStatusType{gateway.StatusFailed}},
+		"statuses":         {Statuses: []gateway.TransactionStatusType{gateway.StatusCompleted, gateway.StatusRefund}},
+		"currency":         {Currency: "EUR"},
+		"reference":        {MerchantReference: "order-3"},
+		"amount range":     {MinAmount: &minAmount, MaxAmount: &maxAmount},
+		"created range":    {CreatedAfter: start, CreatedBefore: start.Add(5 * time.Minute)},
+		"combined":         {Statuses: []gateway.TransactionStatusType{gateway.StatusCompleted}, Currency: "USD", MinAmount: &minAmount},
+		"unknown currency": {Currency: "GBP"},
+	}
+
+	for name, filter := range filters {
+		for _, sortBy := range []gateway.SortField{gateway.SortByCreatedAt, gateway.SortByAmount} {
+			for _, order := range []gateway.SortOrder{gateway.SortAscending, gateway.SortDescending} {
+				t.Run(fmt.Sprintf("%s/%s/%s", name, sortBy, order), func(t *testing.T) {
+					q, err := gateway.ListQuery{Filter: filter, SortBy: sortBy, Order: order, Limit: 3}.Normalize()
+					if err != nil {
+						t.Fatalf("Normalize: %v", err)
+					}
+
+					want := linearIDs(transactions, q)
+
+					var got []string
+					for range len(transactions) {
+						ids := scanIDs(x, transactions, q)
+						page := q.Page(lookup(transactions, ids), false)
+						for _, status := range page.Transactions {
+							got = append(got, status.TransactionID)
+						}
+						if page.NextCursor == "" {
+							break
+						}
+
+						q.Cursor = page.NextCursor
+						if q, err = q.Normalize(); err != nil {
+							t.Fatalf("Normalize(cursor): %v", err)
+						}
+					}
+
+					if !slices.Equal(got, want) {
+						t.Fatalf("paged index scan = %v, want %v", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestIndexRemoveDropsEntries(t *testing.T) {
+	x, transactions := newTestIndex()
+
+	removed := transactions["txn-03"]
+	x.remove(removed)
+	delete(transactions, removed.TransactionID)
+
+	q, err := gateway.ListQuery{Filter: gateway.ListFilter{MerchantReference: removed.MerchantReference}}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+
+	if ids := scanIDs(x, transactions, q); slices.Contains(ids, removed.TransactionID) || len(ids) != len(linearIDs(transactions, q)) {
+		t.Fatalf("scan after remove = %v", ids)
+	}
+	if len(x.byCreatedAt) != len(transactions) || len(x.byAmount) != len(transactions) {
+		t.Fatalf("sorted indexes hold %d and %d entries, want %d", len(x.byCreatedAt), len(x.byAmount), len(transactions))
+	}
+}
@@ -0,0 +1,424 @@
+package engine
+
+import (
+	"context"
+	"factory-method/internal/payment/gateway"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InMemoryTransactionStore struct {
+	storeConfig
+	transactions map[string]*gateway.TransactionStatus
+	history      map[string][]gateway.TransactionEvent
+	index        *transactionIndex
+	idempotency  *gateway.IdempotencyKeys
+	mu           sync.RWMutex
+}
+
+func NewInMemoryTransactionStore(provider string, opts ...StoreOption) *InMemoryTransactionStore {
+	c := newStoreConfig(provider, opts)
+
+	return &InMemoryTransactionStore{
+		storeConfig:  c,
+		transactions: make(map[string]*gateway.TransactionStatus),
+		history:      make(map[string][]gateway.TransactionEvent),
+		index:        newTransactionIndex(),
+		idempotency:  gateway.NewIdempotencyKeys(c.idempotencyRetention),
+	}
+}
+
+func (s *InMemoryTransactionStore) Save(ctx context.Context, saveTransaction *SaveTransaction) (*gateway.TransactionStatus, error) {
+	if saveTransaction.TransactionID == "" {
+		saveTransaction.TransactionID = generateTransactionID()
+	}
+
+	handler := s.makeSaveHandler(saveTransaction)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpSave),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *InMemoryTransactionStore) Get(ctx context.Context, id string) (*gateway.TransactionStatus, error) {
+	handler := s.makeGetHandler(id)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpGet),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *InMemoryTransactionStore) Update(ctx context.Context, updateTransaction UpdateTransaction) (*gateway.TransactionStatus, error) {
+	handler := s.makeUpdateHandler(updateTransaction)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpUpdate),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *InMemoryTransactionStore) FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, error) {
+	handler := s.makeFindByIdempotencyKeyHandler(key, fingerprint)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpFindByIdempotencyKey),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *InMemoryTransactionStore) GetHistory(ctx context.Context, id string) ([]gateway.TransactionEvent, error) {
+	var history []gateway.TransactionEvent
+
+	handler := s.makeGetHistoryHandler(id, &history)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpGetHistory),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	if _, err := wrappedHandler(ctx); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (s *InMemoryTransactionStore) List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error) {
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var page *gateway.TransactionPage
+
+	handler := s.makeListHandler(query, &page)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpList),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	if _, err := wrappedHandler(ctx); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (s *InMemoryTransactionStore) makeSaveHandler(saveTransaction *SaveTransaction) transactionHandler {
+	saveHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		if existing, err := s.getTransaction(saveTransaction.TransactionID); err == nil {
+			return existing, nil
+		}
+
+		now := time.Now().UTC()
+		failed := s.faults.ShouldFail(gateway.OpPaymentOutcome)
+
+		status, events, err := s.newTransaction(ctx, saveTransaction, failed, now)
+		if err != nil {
+			return nil, err
+		}
+
+		commit := gateway.Commit{
+			TransactionID:  status.TransactionID,
+			Transaction:    status,
+			Events:         events,
+			IdempotencyKey: saveTransaction.IdempotencyKey,
+			Fingerprint:    saveTransaction.Fingerprint,
+			CommittedAt:    now,
+		}
+
+		if err := s.commit(commit); err != nil {
+			return nil, s.errorf("failed to persist transaction: %w", err)
+		}
+
+		s.saveTransaction(status)
+		s.appendEvents(status.TransactionID, events...)
+		s.notify(commit)
+
+		return status.Clone(), nil
+	}
+
+	return s.withIdempotency(saveTransaction.IdempotencyKey, saveTransaction.Fingerprint, saveHandler)
+}
+
+func (s *InMemoryTransactionStore) makeGetHandler(id string) transactionHandler {
+	getHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		return s.getTransaction(id)
+	}
+
+	return getHandler
+}
+
+func (s *InMemoryTransactionStore) makeUpdateHandler(updateTransaction UpdateTransaction) transactionHandler {
+	updateHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		for {
+			stored, err := s.getTransaction(updateTransaction.TransactionID)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.checkVersion(stored, updateTransaction); err != nil {
+				return nil, err
+			}
+
+			now := time.Now().UTC()
+
+			t, events, err := s.applyTransactionUpdate(ctx, stored, updateTransaction, now)
+			if err != nil {
+				commitErr := s.commit(gateway.Commit{
+					TransactionID: stored.TransactionID,
+					Events:        events,
+					CommittedAt:   now,
+				})
+				if commitErr == nil {
+					s.appendEvents(stored.TransactionID, events...)
+				}
+
+				return nil, err
+			}
+
+			commit := gateway.Commit{
+				TransactionID:  t.TransactionID,
+				Transaction:    t,
+				Events:         events,
+				IdempotencyKey: updateTransaction.IdempotencyKey,
+				Fingerprint:    updateTransaction.Fingerprint,
+				CommittedAt:    now,
+			}
+
+			swapped, err := s.compareAndSwap(stored.Version, t, events, commit)
+			if err != nil {
+				return nil, s.errorf("failed to persist transaction: %w", err)
+			}
+
+			if swapped {
+				s.notify(commit)
+				return t.Clone(), nil
+			}
+		}
+	}
+
+	return s.withIdempotency(updateTransaction.IdempotencyKey, updateTransaction.Fingerprint, updateHandler)
+}
+
+func (s *InMemoryTransactionStore) makeGetHistoryHandler(id string, history *[]gateway.TransactionEvent) transactionHandler {
+	getHistoryHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		t, err := s.getTransaction(id)
+		if err != nil {
+			return nil, err
+		}
+
+		*history = s.getHistory(id)
+
+		return t, nil
+	}
+
+	return getHistoryHandler
+}
+
+func (s *InMemoryTransactionStore) makeListHandler(query gateway.ListQuery, page **gateway.TransactionPage) transactionHandler {
+	listHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		if query.Filter.Provider != "" && query.Filter.Provider != s.provider {
+			*page = query.Page(nil, false)
+			return nil, nil
+		}
+
+		*page = query.Page(s.listTransactions(query), false)
+
+		return nil, nil
+	}
+
+	return listHandler
+}
+
+func (s *InMemoryTransactionStore) makeFindByIdempotencyKeyHandler(key string, fingerprint string) transactionHandler {
+	findHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		transaction, err := s.idempotency.Lookup(key, fingerprint)
+		if err != nil {
+			return nil, s.errorf("%w", err)
+		}
+
+		return transaction, nil
+	}
+
+	return findHandler
+}
+
+func (s *InMemoryTransactionStore) withIdempotency(key string, fingerprint string, next transactionHandler) transactionHandler {
+	if key == "" {
+		return next
+	}
+
+	return func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		replayed, err := s.idempotency.Begin(key, fingerprint)
+		if err != nil {
+			return nil, s.errorf("%w", err)
+		}
+
+		if replayed != nil {
+			return replayed, nil
+		}
+
+		status, err := next(ctx)
+		if err != nil {
+			s.idempotency.Abort(key)
+			return nil, err
+		}
+
+		s.idempotency.Complete(key, status)
+
+		return status, nil
+	}
+}
+
+func (s *InMemoryTransactionStore) getTransaction(id string) (*gateway.TransactionStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transaction, ok := s.transactions[id]
+	if !ok {
+		return nil, s.transactionNotFound(id)
+	}
+
+	return transaction.Clone(), nil
+}
+
+func (s *InMemoryTransactionStore) listTransactions(query gateway.ListQuery) []*gateway.TransactionStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	transactions := make([]*gateway.TransactionStatus, 0, query.Limit+1)
+
+	s.index.scan(query, s.transactions, func(t *gateway.TransactionStatus) bool {
+		transactions = append(transactions, t.Clone())
+		return len(transactions) <= query.Limit
+	})
+
+	return transactions
+}
+
+func (s *InMemoryTransactionStore) saveTransaction(t *gateway.TransactionStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.storeLocked(t)
+}
+
+func (s *InMemoryTransactionStore) storeLocked(t *gateway.TransactionStatus) {
+	if current, ok := s.transactions[t.TransactionID]; ok {
+		s.index.remove(current)
+	}
+
+	s.transactions[t.TransactionID] = t
+	s.index.add(t)
+}
+
+func (s *InMemoryTransactionStore) compareAndSwap(version int64, t *gateway.TransactionStatus, events []gateway.TransactionEvent, commit gateway.Commit) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.transactions[t.TransactionID]
+	if !ok || current.Version != version {
+		return false, nil
+	}
+
+	if err := s.commit(commit); err != nil {
+		return false, err
+	}
+
+	s.storeLocked(t)
+	s.history[t.TransactionID] = append(s.history[t.TransactionID], events...)
+
+	return true, nil
+}
+
+func (s *InMemoryTransactionStore) RestoreTransaction(t *gateway.TransactionStatus) {
+	restored := t.Clone()
+	if restored.Provider == "" {
+		restored.Provider = s.provider
+	}
+
+	s.saveTransaction(restored)
+}
+
+func (s *InMemoryTransactionStore) RestoreEvents(transactionID string, events []gateway.TransactionEvent) {
+	s.appendEvents(transactionID, events...)
+}
+
+func (s *InMemoryTransactionStore) RestoreIdempotencyKey(key, fingerprint string, result *gateway.TransactionStatus, recordedAt time.Time) {
+	s.idempotency.Remember(key, fingerprint, result, recordedAt)
+}
+
+func (s *InMemoryTransactionStore) getHistory(id string) []gateway.TransactionEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := s.history[id]
+	history := make([]gateway.TransactionEvent, len(events))
+	copy(history, events)
+
+	return history
+}
+
+func (s *InMemoryTransactionStore) appendEvents(id string, events ...gateway.TransactionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history[id] = append(s.history[id], events...)
+}
+
+func newEvent(
+	t *gateway.TransactionStatus,
+	eventType gateway.EventType,
+	actor string,
+	at time.Time,
+	apply func(*gateway.TransactionEvent),
+) gateway.TransactionEvent {
+	event := gateway.TransactionEvent{
+		EventID:       generateTransactionID(),
+		TransactionID: t.TransactionID,
+		Type:          eventType,
+		Actor:         actor,
+		CreatedAt:     at,
+	}
+
+	apply(&event)
+
+	return event
+}
+
+func generateTransactionID() string {
+	id, _ := uuid.NewRandom()
+	return id.String()
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+func newTestStore(t *testing.T) (*InMemoryTransactionStore, *gateway.TransactionStatus) {
+	t.Helper()
+
+	store := NewInMemoryTransactionStore("test",
+		WithFaultInjector(gateway.NewFaultInjector(gateway.DisabledFaultConfig())),
+		WithRetryPolicy(NoRetryPolicy()),
+	)
+
+	saved, err := store.Save(context.Background(), &SaveTransaction{
+		Amount:   money.New(1000, "USD"),
+		Fee:      money.Zero("USD"),
+		Metadata: gateway.Metadata{"order": "42"},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	return store, saved
+}
+
+func refundUpdate(id string, amount int64, version int64) UpdateTransaction {
+	return UpdateTransaction{
+		TransactionID:   id,
+		ExpectedVersion: version,
+		Status:          gateway.StatusPartiallyRefunded,
+		Refund:          &RefundTransaction{Amount: money.New(amount, "USD")},
+	}
+}
+
+func TestConcurrentPartialRefundsAreAllApplied(t *testing.T) {
+	store, saved := newTestStore(t)
+	ctx := context.Background()
+
+	const refunds = 40
+
+	var wg sync.WaitGroup
+	for range refunds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Update(ctx, refundUpdate(saved.TransactionID, 25, 0)); err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.Get(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if got.RefundedAmount.Amount() != 1000 || len(got.Refunds) != refunds {
+		t.Fatalf("refunded %s across %d refunds, want 10.00 USD across %d", got.RefundedAmount, len(got.Refunds), refunds)
+	}
+	if got.Version != saved.Version+refunds {
+		t.Fatalf("version = %d, want %d", got.Version, saved.Version+refunds)
+	}
+
+	if _, err := store.Update(ctx, refundUpdate(saved.TransactionID, 1, 0)); err == nil {
+		t.Fatal("refund beyond captured amount succeeded")
+	}
+}
+
+func TestConcurrentStaleVersionUpdatesConflict(t *testing.T) {
+	store, saved := newTestStore(t)
+	ctx := context.Background()
+
+	const writers = 20
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		conflicts int
+	)
+
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := store.Update(ctx, refundUpdate(saved.TransactionID, 10, saved.Version))
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, gateway.ErrVersionConflict):
+				conflicts++
+			default:
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 || conflicts != writers-1 {
+		t.Fatalf("%d updates succeeded and %d conflicted, want 1 and %d", succeeded, conflicts, writers-1)
+	}
+
+	got, err := store.Get(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.RefundedAmount.Amount() != 10 || got.Version != saved.Version+1 {
+		t.Fatalf("refunded %s at version %d, want 0.10 USD at version %d", got.RefundedAmount, got.Version, saved.Version+1)
+	}
+}
+
+func TestSnapshotsAreIsolatedFromStore(t *testing.T) {
+	store, saved := newTestStore(t)
+	ctx := context.Background()
+
+	saved.Status = gateway.StatusFailed
+	saved.Metadata["order"] = "tampered"
+
+	updated, err := store.Update(ctx, refundUpdate(saved.TransactionID, 100, 0))
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	updated.Refunds[0].Amount = money.New(999, "USD")
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			snapshot, err := store.Get(ctx, saved.TransactionID)
+			if err != nil {
+				t.Errorf("Get: %v", err)
+				return
+			}
+			snapshot.Refunds = append(snapshot.Refunds, gateway.RefundRecord{})
+			snapshot.Metadata["order"] = "tampered"
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := store.Update(ctx, refundUpdate(saved.TransactionID, 10, 0)); err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.Get(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	if got.Status != gateway.StatusPartiallyRefunded {
+		t.Fatalf("status = %q, want %q", got.Status, gateway.StatusPartiallyRefunded)
+	}
+	if got.Metadata["order"] != "42" {
+		t.Fatalf("metadata order = %q, want 42", got.Metadata["order"])
+	}
+	if len(got.Refunds) != 9 || got.Refunds[0].Amount.Amount() != 100 {
+		t.Fatalf("refunds = %d with first %s, want 9 with first 1.00 USD", len(got.Refunds), got.Refunds[0].Amount)
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"fmt"
+
+	"factory-method/internal/payment/card"
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+type Provider struct {
+	Name            string
+	ValidationRules []ValidationRule
+	AcceptedBrands  []card.Brand
+	Authenticator   Authenticator
+	Fee             FeeRule
+	DeclineCodes    map[gateway.DeclineReason]string
+}
+
+type FeeRule func(amount money.Money) money.Money
+
+func NoFee(amount money.Money) money.Money {
+	return money.Zero(amount.Currency())
+}
+
+func PercentageFee(basisPoints int64, fixed ...money.Money) FeeRule {
+	fixedByCurrency := make(map[string]int64, len(fixed))
+	for _, f := range fixed {
+		fixedByCurrency[f.Currency()] = f.Amount()
+	}
+
+	return func(amount money.Money) money.Money {
+		variable := (amount.Amount()*basisPoints + 5000) / 10000
+		return money.New(variable+fixedByCurrency[amount.Currency()], amount.Currency())
+	}
+}
+
+func (p Provider) fee(amount money.Money) money.Money {
+	if p.Fee == nil {
+		return NoFee(amount)
+	}
+	return p.Fee(amount)
+}
+
+func (p Provider) errorf(format string, args ...any) error {
+	return fmt.Errorf(p.Name+": "+format, args...)
+}
+
+func (p Provider) fail(kind gateway.ErrorKind, format string, args ...any) error {
+	return gateway.Errorf(kind, p.Name, format, args...)
+}
+
+func (p Provider) decline(reason gateway.DeclineReason, message string) error {
+	code, ok := p.DeclineCodes[reason]
+	if !ok {
+		code = string(reason)
+	}
+	return gateway.Declined(p.Name, code, message)
+}
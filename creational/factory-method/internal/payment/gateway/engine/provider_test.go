@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+
+	"factory-method/pkg/money"
+)
+
+func TestPercentageFeeKeysFixedFeeByCurrency(t *testing.T) {
+	fee := PercentageFee(290, money.New(30, "USD"), money.New(25, "EUR"), money.New(40, "JPY"))
+
+	tests := []struct {
+		amount money.Money
+		want   money.Money
+	}{
+		{money.New(10000, "USD"), money.New(320, "USD")},
+		{money.New(10000, "EUR"), money.New(315, "EUR")},
+		{money.New(10000, "JPY"), money.New(330, "JPY")},
+		{money.New(10000, "KWD"), money.New(290, "KWD")},
+		{money.New(17, "USD"), money.New(30, "USD")},
+		{money.New(18, "USD"), money.New(31, "USD")},
+	}
+
+	for _, tt := range tests {
+		if got := fee(tt.amount); !got.Equal(tt.want) {
+			t.Fatalf("fee(%s) = %s, want %s", tt.amount, got, tt.want)
+		}
+	}
+
+	if got := PercentageFee(100)(money.New(500, "USD")); !got.Equal(money.New(5, "USD")) {
+		t.Fatalf("fee without fixed component = %s, want 0.05 USD", got)
+	}
+}
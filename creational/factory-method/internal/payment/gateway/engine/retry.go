@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+	Budget       *RetryBudget
+	Retryable    func(error) bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  3,
+		InitialDelay: 50 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		Budget:       NewRetryBudget(0.2, 10),
+		Retryable:    IsRetryable,
+	}
+}
+
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+func IsRetryable(err error) bool {
+	return errors.Is(err, gateway.ErrTransient)
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < retry; i++ {
+		delay *= p.Multiplier
+		if p.MaxDelay > 0 && delay >= float64(p.MaxDelay) {
+			delay = float64(p.MaxDelay)
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	return time.Duration(delay)
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return IsRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+type RetryBudget struct {
+	ratio  float64
+	max    float64
+	tokens float64
+	mu     sync.Mutex
+}
+
+func NewRetryBudget(ratio float64, burst int) *RetryBudget {
+	return &RetryBudget{
+		ratio:  ratio,
+		max:    float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (b *RetryBudget) fresh() *RetryBudget {
+	if b == nil {
+		return nil
+	}
+
+	return NewRetryBudget(b.ratio, int(b.max))
+}
+
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.max, b.tokens+b.ratio)
+}
+
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+func (c storeConfig) withRetry(next transactionHandler) transactionHandler {
+	policy := c.retry
+
+	return func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		policy.Budget.deposit()
+
+		for attempt := 1; ; attempt++ {
+			status, err := next(ctx)
+			if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+				return status, err
+			}
+
+			delay := policy.backoff(attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+				return nil, err
+			}
+
+			if !policy.Budget.withdraw() {
+				return nil, err
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, err
+			case <-timer.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+func TestStoresSharingRetryPolicyHaveSeparateBudgets(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:  2,
+		InitialDelay: time.Millisecond,
+		Multiplier:   1,
+		Budget:       NewRetryBudget(0, 1),
+	}
+
+	newStore := func(name string, gets ...bool) *InMemoryTransactionStore {
+		return NewInMemoryTransactionStore(name,
+			WithFaultInjector(gateway.NewFaultInjector(gateway.FaultConfig{
+				Script: map[gateway.Operation][]bool{gateway.OpGet: gets},
+			})),
+			WithRetryPolicy(policy),
+		)
+	}
+
+	ctx := context.Background()
+	failing, healthy := newStore("failing", true, true), newStore("healthy", true)
+
+	if _, err := failing.Get(ctx, "missing"); err == nil {
+		t.Fatal("Get on failing store succeeded, want transient error")
+	}
+
+	saved, err := healthy.Save(ctx, &SaveTransaction{Amount: money.New(100, "USD")})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := healthy.Get(ctx, saved.TransactionID); err != nil {
+		t.Fatalf("Get on healthy store = %v, want retry funded by its own budget", err)
+	}
+}
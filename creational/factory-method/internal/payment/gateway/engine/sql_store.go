@@ -0,0 +1,389 @@
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/sqlstore"
+	"time"
+)
+
+type SQLTransactionStore struct {
+	storeConfig
+	repo *sqlstore.Repository
+}
+
+func NewSQLTransactionStore(ctx context.Context, provider string, db *sql.DB, dialect sqlstore.Dialect, opts ...StoreOption) (*SQLTransactionStore, error) {
+	c := newStoreConfig(provider, opts)
+
+	if err := sqlstore.Migrate(ctx, db, dialect); err != nil {
+		return nil, c.errorf("%w", err)
+	}
+
+	return &SQLTransactionStore{
+		storeConfig: c,
+		repo:        sqlstore.NewRepository(db, dialect, provider),
+	}, nil
+}
+
+func (s *SQLTransactionStore) Save(ctx context.Context, saveTransaction *SaveTransaction) (*gateway.TransactionStatus, error) {
+	if saveTransaction.TransactionID == "" {
+		saveTransaction.TransactionID = generateTransactionID()
+	}
+
+	handler := s.makeSaveHandler(saveTransaction)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpSave),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *SQLTransactionStore) Get(ctx context.Context, id string) (*gateway.TransactionStatus, error) {
+	handler := s.makeGetHandler(id)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpGet),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *SQLTransactionStore) Update(ctx context.Context, updateTransaction UpdateTransaction) (*gateway.TransactionStatus, error) {
+	handler := s.makeUpdateHandler(updateTransaction)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpUpdate),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *SQLTransactionStore) FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, error) {
+	handler := s.makeFindByIdempotencyKeyHandler(key, fingerprint)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpFindByIdempotencyKey),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	return wrappedHandler(ctx)
+}
+
+func (s *SQLTransactionStore) GetHistory(ctx context.Context, id string) ([]gateway.TransactionEvent, error) {
+	var history []gateway.TransactionEvent
+
+	handler := s.makeGetHistoryHandler(id, &history)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpGetHistory),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	if _, err := wrappedHandler(ctx); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (s *SQLTransactionStore) List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error) {
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var page *gateway.TransactionPage
+
+	handler := s.makeListHandler(query, &page)
+	middlewares := []Middleware{
+		withContextCheck,
+		s.withNetworkSimulator(gateway.OpList),
+		s.withRetry,
+		s.withCircuitBreaker,
+	}
+
+	wrappedHandler := applyMiddlewares(handler, middlewares...)
+
+	if _, err := wrappedHandler(ctx); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (s *SQLTransactionStore) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	purged, err := s.repo.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC())
+	if err != nil {
+		return 0, s.errorf("%w", err)
+	}
+
+	return purged, nil
+}
+
+func (s *SQLTransactionStore) makeSaveHandler(saveTransaction *SaveTransaction) transactionHandler {
+	saveHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		now := time.Now().UTC()
+
+		var (
+			result    *gateway.TransactionStatus
+			committed gateway.Commit
+		)
+
+		err := s.repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+			replayed, err := s.replayIdempotencyKey(ctx, tx, saveTransaction.IdempotencyKey, saveTransaction.Fingerprint, now)
+			if err != nil || replayed != nil {
+				result = replayed
+				return err
+			}
+
+			existing, err := tx.LockTransaction(ctx, saveTransaction.TransactionID)
+			if err == nil {
+				result = existing
+				return nil
+			}
+			if !errors.Is(err, sqlstore.ErrTransactionNotFound) {
+				return s.errorf("%w", err)
+			}
+
+			failed := s.faults.ShouldFail(gateway.OpPaymentOutcome)
+
+			status, events, err := s.newTransaction(ctx, saveTransaction, failed, now)
+			if err != nil {
+				return err
+			}
+
+			if err := tx.InsertTransaction(ctx, status); err != nil {
+				return s.errorf("failed to persist transaction: %w", err)
+			}
+
+			committed = gateway.Commit{
+				TransactionID:  status.TransactionID,
+				Transaction:    status,
+				Events:         events,
+				IdempotencyKey: saveTransaction.IdempotencyKey,
+				Fingerprint:    saveTransaction.Fingerprint,
+				CommittedAt:    now,
+			}
+			if err := s.persist(ctx, tx, committed); err != nil {
+				return err
+			}
+
+			result = status
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		s.notify(committed)
+
+		return result, nil
+	}
+
+	return saveHandler
+}
+
+func (s *SQLTransactionStore) makeGetHandler(id string) transactionHandler {
+	getHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		t, err := s.repo.GetTransaction(ctx, id)
+		if err != nil {
+			return nil, s.lookupError(id, err)
+		}
+
+		return t, nil
+	}
+
+	return getHandler
+}
+
+func (s *SQLTransactionStore) makeUpdateHandler(updateTransaction UpdateTransaction) transactionHandler {
+	updateHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		now := time.Now().UTC()
+
+		var (
+			result    *gateway.TransactionStatus
+			committed gateway.Commit
+			failure   error
+		)
+
+		err := s.repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+			replayed, err := s.replayIdempotencyKey(ctx, tx, updateTransaction.IdempotencyKey, updateTransaction.Fingerprint, now)
+			if err != nil || replayed != nil {
+				result = replayed
+				return err
+			}
+
+			stored, err := tx.LockTransaction(ctx, updateTransaction.TransactionID)
+			if err != nil {
+				return s.lookupError(updateTransaction.TransactionID, err)
+			}
+
+			if err := s.checkVersion(stored, updateTransaction); err != nil {
+				return err
+			}
+
+			t, events, err := s.applyTransactionUpdate(ctx, stored, updateTransaction, now)
+			if err != nil {
+				failure = err
+
+				return s.persist(ctx, tx, gateway.Commit{
+					TransactionID: stored.TransactionID,
+					Events:        events,
+					CommittedAt:   now,
+				})
+			}
+
+			if err := tx.UpdateTransaction(ctx, stored, t); err != nil {
+				if errors.Is(err, sqlstore.ErrStaleVersion) {
+					return s.fail(gateway.KindConflict, "%w", gateway.ErrVersionConflict)
+				}
+				return s.errorf("failed to persist transaction: %w", err)
+			}
+
+			committed = gateway.Commit{
+				TransactionID:  t.TransactionID,
+				Transaction:    t,
+				Events:         events,
+				IdempotencyKey: updateTransaction.IdempotencyKey,
+				Fingerprint:    updateTransaction.Fingerprint,
+				CommittedAt:    now,
+			}
+			if err := s.persist(ctx, tx, committed); err != nil {
+				return err
+			}
+
+			result = t
+
+			return nil
+		})
+		if failure != nil {
+			return nil, failure
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s.notify(committed)
+
+		return result, nil
+	}
+
+	return updateHandler
+}
+
+func (s *SQLTransactionStore) makeGetHistoryHandler(id string, history *[]gateway.TransactionEvent) transactionHandler {
+	getHistoryHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		t, err := s.repo.GetTransaction(ctx, id)
+		if err != nil {
+			return nil, s.lookupError(id, err)
+		}
+
+		events, err := s.repo.GetHistory(ctx, id)
+		if err != nil {
+			return nil, s.errorf("%w", err)
+		}
+
+		*history = events
+
+		return t, nil
+	}
+
+	return getHistoryHandler
+}
+
+func (s *SQLTransactionStore) makeListHandler(query gateway.ListQuery, page **gateway.TransactionPage) transactionHandler {
+	listHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		if query.Filter.Provider != "" && query.Filter.Provider != s.provider {
+			*page = query.Page(nil, false)
+			return nil, nil
+		}
+
+		transactions, err := s.repo.ListTransactions(ctx, query)
+		if err != nil {
+			return nil, s.errorf("%w", err)
+		}
+
+		*page = query.Page(transactions, false)
+
+		return nil, nil
+	}
+
+	return listHandler
+}
+
+func (s *SQLTransactionStore) makeFindByIdempotencyKeyHandler(key string, fingerprint string) transactionHandler {
+	findHandler := func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		transaction, err := s.repo.LookupIdempotencyKey(ctx, key, fingerprint, time.Now().UTC())
+		if err != nil {
+			return nil, s.errorf("%w", err)
+		}
+
+		return transaction, nil
+	}
+
+	return findHandler
+}
+
+func (s *SQLTransactionStore) persist(ctx context.Context, tx *sqlstore.Tx, c gateway.Commit) error {
+	if err := tx.AppendEvents(ctx, c.TransactionID, c.Events...); err != nil {
+		return s.errorf("failed to persist transaction: %w", err)
+	}
+
+	if c.IdempotencyKey != "" {
+		expiresAt := c.CommittedAt.Add(s.idempotencyRetention)
+		if err := tx.PutIdempotencyKey(ctx, c.IdempotencyKey, c.Fingerprint, c.Transaction, c.CommittedAt, expiresAt); err != nil {
+			return s.errorf("failed to persist transaction: %w", err)
+		}
+	}
+
+	if err := s.commit(c); err != nil {
+		return s.errorf("failed to persist transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLTransactionStore) lookupError(id string, err error) error {
+	if errors.Is(err, sqlstore.ErrTransactionNotFound) {
+		return s.transactionNotFound(id)
+	}
+	return s.errorf("%w", err)
+}
+
+func (s *SQLTransactionStore) replayIdempotencyKey(ctx context.Context, tx *sqlstore.Tx, key, fingerprint string, now time.Time) (*gateway.TransactionStatus, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	replayed, err := tx.LookupIdempotencyKey(ctx, key, fingerprint, now)
+	if errors.Is(err, gateway.ErrIdempotencyKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, s.errorf("%w", err)
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/sqlstore/sqltest"
+	"factory-method/pkg/money"
+)
+
+func newTestSQLStore(t *testing.T) *SQLTransactionStore {
+	t.Helper()
+
+	db, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("sqltest.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	store, err := NewSQLTransactionStore(context.Background(), "test", db, sqltest.Dialect,
+		WithFaultInjector(gateway.NewFaultInjector(gateway.DisabledFaultConfig())),
+		WithRetryPolicy(NoRetryPolicy()),
+	)
+	if err != nil {
+		t.Fatalf("NewSQLTransactionStore: %v", err)
+	}
+
+	return store
+}
+
+func testSave(key string) *SaveTransaction {
+	return &SaveTransaction{
+		IdempotencyKey: key,
+		Fingerprint:    "fp-" + key,
+		Amount:         money.New(1000, "USD"),
+		Fee:            money.Zero("USD"),
+	}
+}
+
+func TestSQLStoreSavesGetsAndUpdates(t *testing.T) {
+	store := newTestSQLStore(t)
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, testSave(""))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := store.Get(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != gateway.StatusCompleted || got.Provider != "test" || got.Version != 1 {
+		t.Fatalf("Get = %s on %q at version %d, want completed on test at version 1", got.Status, got.Provider, got.Version)
+	}
+
+	updated, err := store.Update(ctx, refundUpdate(saved.TransactionID, 300, got.Version))
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.RefundedAmount.Amount() != 300 || updated.Version != 2 || len(updated.Refunds) != 1 {
+		t.Fatalf("Update refunded %s at version %d with %d refunds", updated.RefundedAmount, updated.Version, len(updated.Refunds))
+	}
+
+	history, err := store.GetHistory(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(history) == 0 {
+		t.Fatal("GetHistory returned no events")
+	}
+
+	if _, err := store.Get(ctx, "missing"); !errors.Is(err, gateway.ErrNotFound) {
+		t.Fatalf("Get(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLStoreRejectsStaleExpectedVersion(t *testing.T) {
+	store := newTestSQLStore(t)
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, testSave(""))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if _, err := store.Update(ctx, refundUpdate(saved.TransactionID, 100, saved.Version)); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	_, err = store.Update(ctx, refundUpdate(saved.TransactionID, 100, saved.Version))
+	if !errors.Is(err, gateway.ErrVersionConflict) {
+		t.Fatalf("stale Update = %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestSQLStoreReplaysIdempotencyKeys(t *testing.T) {
+	store := newTestSQLStore(t)
+	ctx := context.Background()
+
+	first, err := store.Save(ctx, testSave("order-1"))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	replayed, err := store.Save(ctx, testSave("order-1"))
+	if err != nil {
+		t.Fatalf("replayed Save: %v", err)
+	}
+	if replayed.TransactionID != first.TransactionID {
+		t.Fatalf("replay created %s, want %s", replayed.TransactionID, first.TransactionID)
+	}
+
+	reused := testSave("order-1")
+	reused.Fingerprint = "fp-other"
+	if _, err := store.Save(ctx, reused); !errors.Is(err, gateway.ErrIdempotencyKeyReused) {
+		t.Fatalf("Save with reused key = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	found, err := store.FindByIdempotencyKey(ctx, "order-1", "fp-order-1")
+	if err != nil {
+		t.Fatalf("FindByIdempotencyKey: %v", err)
+	}
+	if found.TransactionID != first.TransactionID {
+		t.Fatalf("FindByIdempotencyKey = %s, want %s", found.TransactionID, first.TransactionID)
+	}
+
+	page, err := store.List(ctx, gateway.ListQuery{Limit: gateway.MaxListLimit})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page.Transactions) != 1 {
+		t.Fatalf("store holds %d transactions, want 1", len(page.Transactions))
+	}
+}
+
+func TestSQLStoreUpdateLocksRow(t *testing.T) {
+	store := newTestSQLStore(t)
+	ctx := context.Background()
+
+	saved, err := store.Save(ctx, testSave(""))
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	const refunds = 20
+
+	var wg sync.WaitGroup
+	for range refunds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Update(ctx, refundUpdate(saved.TransactionID, 50, 0)); err != nil {
+				t.Errorf("Update: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := store.Get(ctx, saved.TransactionID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.RefundedAmount.Amount() != 1000 || len(got.Refunds) != refunds || got.Version != saved.Version+refunds {
+		t.Fatalf("refunded %s with %d refunds at version %d, want 10.00 USD with %d at version %d",
+			got.RefundedAmount, len(got.Refunds), got.Version, refunds, saved.Version+refunds)
+	}
+}
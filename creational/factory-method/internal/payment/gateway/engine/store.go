@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+type transactionHandler func(ctx context.Context) (*gateway.TransactionStatus, error)
+
+type Middleware func(transactionHandler) transactionHandler
+
+type storeConfig struct {
+	provider             string
+	idempotencyRetention time.Duration
+	faults               *gateway.FaultInjector
+	commitHook           gateway.CommitHook
+	commitListener       gateway.CommitListener
+	retry                RetryPolicy
+	breaker              *gateway.CircuitBreaker
+}
+
+type StoreOption func(*storeConfig)
+
+func WithIdempotencyRetention(retention time.Duration) StoreOption {
+	return func(c *storeConfig) {
+		c.idempotencyRetention = retention
+	}
+}
+
+func WithFaultInjector(faults *gateway.FaultInjector) StoreOption {
+	return func(c *storeConfig) {
+		c.faults = faults
+	}
+}
+
+func WithCommitHook(hook gateway.CommitHook) StoreOption {
+	return func(c *storeConfig) {
+		c.commitHook = hook
+	}
+}
+
+func WithCommitListener(listener gateway.CommitListener) StoreOption {
+	return func(c *storeConfig) {
+		c.commitListener = listener
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) StoreOption {
+	return func(c *storeConfig) {
+		policy.Budget = policy.Budget.fresh()
+		c.retry = policy
+	}
+}
+
+func WithCircuitBreaker(breaker *gateway.CircuitBreaker) StoreOption {
+	return func(c *storeConfig) {
+		c.breaker = breaker
+	}
+}
+
+func newStoreConfig(provider string, opts []StoreOption) storeConfig {
+	c := storeConfig{
+		provider:             provider,
+		idempotencyRetention: gateway.DefaultIdempotencyRetention,
+		faults:               gateway.NewFaultInjector(gateway.DefaultFaultConfig()),
+		retry:                DefaultRetryPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+func (c storeConfig) errorf(format string, args ...any) error {
+	return fmt.Errorf(c.provider+": "+format, args...)
+}
+
+func (c storeConfig) fail(kind gateway.ErrorKind, format string, args ...any) error {
+	return gateway.Errorf(kind, c.provider, format, args...)
+}
+
+func (c storeConfig) transactionNotFound(id string) error {
+	return c.fail(gateway.KindNotFound, "transaction %q not found", id)
+}
+
+func (c storeConfig) commit(commit gateway.Commit) error {
+	if c.commitHook == nil {
+		return nil
+	}
+	return c.commitHook(commit)
+}
+
+func (c storeConfig) notify(commit gateway.Commit) {
+	if c.commitListener == nil || commit.Transaction == nil {
+		return
+	}
+	c.commitListener(commit)
+}
+
+func applyMiddlewares(handler transactionHandler, middlewares ...Middleware) transactionHandler {
+	for _, middleware := range middlewares {
+		handler = middleware(handler)
+	}
+
+	return handler
+}
+
+func withContextCheck(next transactionHandler) transactionHandler {
+	return func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return next(ctx)
+	}
+}
+
+func (c storeConfig) withNetworkSimulator(op gateway.Operation) Middleware {
+	return func(next transactionHandler) transactionHandler {
+		return func(ctx context.Context) (*gateway.TransactionStatus, error) {
+			if c.faults.ShouldFail(op) {
+				return nil, c.fail(gateway.KindTransient, "network error")
+			}
+
+			if err := c.faults.Wait(ctx); err != nil {
+				return nil, err
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+func (c storeConfig) withCircuitBreaker(next transactionHandler) transactionHandler {
+	return func(ctx context.Context) (*gateway.TransactionStatus, error) {
+		admission, ok := c.breaker.Allow()
+		if !ok {
+			return nil, c.fail(gateway.KindTransient, "%w", gateway.ErrProviderUnavailable)
+		}
+
+		status, err := next(ctx)
+		c.breaker.Record(admission, errors.Is(err, gateway.ErrTransient) || errors.Is(err, context.DeadlineExceeded))
+
+		return status, err
+	}
+}
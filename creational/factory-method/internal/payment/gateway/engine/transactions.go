@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"context"
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+	"time"
+)
+
+func (c storeConfig) newTransaction(ctx context.Context, saveTransaction *SaveTransaction, failed bool, now time.Time) (*gateway.TransactionStatus, []gateway.TransactionEvent, error) {
+	status := &gateway.TransactionStatus{
+		TransactionID:     saveTransaction.TransactionID,
+		Provider:          c.provider,
+		Version:           1,
+		Status:            gateway.StatusPending,
+		Amount:            saveTransaction.Amount,
+		CapturedAmount:    money.Zero(saveTransaction.Amount.Currency()),
+		RefundedAmount:    money.Zero(saveTransaction.Amount.Currency()),
+		Fee:               money.Zero(saveTransaction.Amount.Currency()),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		ErrorMessage:      "",
+		MerchantReference: saveTransaction.MerchantReference,
+		Metadata:          saveTransaction.Metadata.Clone(),
+	}
+
+	switch {
+	case failed:
+		status.Status = gateway.StatusFailed
+		status.ErrorMessage = c.errorf("failed save other").Error()
+	case saveTransaction.Authorize:
+		status.Status = gateway.StatusAuthorized
+		status.AuthorizationExpiresAt = saveTransaction.AuthorizationExpiresAt
+	default:
+		status.Status = gateway.StatusCompleted
+		status.CapturedAmount = saveTransaction.Amount
+	}
+
+	if status.Status != gateway.StatusFailed {
+		status.Fee = saveTransaction.Fee
+	}
+
+	if err := gateway.ValidateTransition(gateway.StatusPending, status.Status); err != nil {
+		return nil, nil, c.fail(gateway.KindConflict, "%w", err)
+	}
+
+	actor := gateway.ActorFromContext(ctx)
+
+	events := []gateway.TransactionEvent{
+		newEvent(status, gateway.EventCreated, actor, now, func(e *gateway.TransactionEvent) {
+			e.ToStatus = gateway.StatusPending
+			e.Amount = status.Amount
+		}),
+		newEvent(status, gateway.EventStatusChanged, actor, now, func(e *gateway.TransactionEvent) {
+			e.FromStatus = gateway.StatusPending
+			e.ToStatus = status.Status
+			e.Reason = status.ErrorMessage
+		}),
+	}
+
+	if status.Status == gateway.StatusFailed {
+		events = append(events, newEvent(status, gateway.EventError, actor, now, func(e *gateway.TransactionEvent) {
+			e.Reason = status.ErrorMessage
+		}))
+	}
+
+	return status, events, nil
+}
+
+func (c storeConfig) applyTransactionUpdate(ctx context.Context, stored *gateway.TransactionStatus, updateTransaction UpdateTransaction, now time.Time) (*gateway.TransactionStatus, []gateway.TransactionEvent, error) {
+	t := stored.Clone()
+	actor := gateway.ActorFromContext(ctx)
+	from := t.Status
+
+	var events []gateway.TransactionEvent
+
+	if updateTransaction.Refund != nil {
+		events = append(events, newEvent(t, gateway.EventRefundRequested, actor, now, func(e *gateway.TransactionEvent) {
+			e.FromStatus = from
+			e.Amount = updateTransaction.Refund.Amount
+			e.Reason = updateTransaction.Refund.Reason
+		}))
+	}
+
+	if err := c.applyUpdate(t, updateTransaction, now); err != nil {
+		events = append(events, newEvent(t, gateway.EventError, actor, now, func(e *gateway.TransactionEvent) {
+			e.FromStatus = from
+			e.ToStatus = updateTransaction.Status
+			e.Reason = err.Error()
+		}))
+
+		return nil, events, err
+	}
+
+	events = append(events, newEvent(t, gateway.EventStatusChanged, actor, now, func(e *gateway.TransactionEvent) {
+		e.FromStatus = from
+		e.ToStatus = t.Status
+		e.Reason = updateTransaction.Reason
+		if updateTransaction.Capture != nil {
+			e.Amount = updateTransaction.Capture.Amount
+		}
+		if updateTransaction.Refund != nil {
+			e.Amount = updateTransaction.Refund.Amount
+			e.Reason = updateTransaction.Refund.Reason
+		}
+	}))
+
+	return t, events, nil
+}
+
+func (c storeConfig) applyUpdate(t *gateway.TransactionStatus, updateTransaction UpdateTransaction, now time.Time) error {
+	if err := gateway.ValidateTransition(t.Status, updateTransaction.Status); err != nil {
+		return c.fail(gateway.KindConflict, "%w", err)
+	}
+
+	if updateTransaction.Capture != nil {
+		if t.Status != gateway.StatusAuthorized {
+			return c.fail(gateway.KindConflict, "transaction is not authorized")
+		}
+
+		if cmp, err := updateTransaction.Capture.Amount.Cmp(t.Amount); err != nil || cmp > 0 {
+			return c.fail(gateway.KindConflict, "capture amount exceeds authorized amount")
+		}
+	}
+
+	if updateTransaction.Refund != nil {
+		refunded, err := t.RefundedAmount.Add(updateTransaction.Refund.Amount)
+		if err != nil {
+			return err
+		}
+
+		if cmp, err := refunded.Cmp(t.CapturedAmount); err != nil || cmp > 0 {
+			return c.fail(gateway.KindConflict, "refund total exceeds transaction amount")
+		}
+
+		t.RefundedAmount = refunded
+		t.Refunds = append(t.Refunds, gateway.RefundRecord{
+			RefundID:          generateTransactionID(),
+			Amount:            updateTransaction.Refund.Amount,
+			Reason:            updateTransaction.Refund.Reason,
+			MerchantReference: updateTransaction.Refund.MerchantReference,
+			Metadata:          updateTransaction.Refund.Metadata.Clone(),
+			CreatedAt:         now,
+		})
+	}
+
+	if updateTransaction.Capture != nil {
+		t.CapturedAmount = updateTransaction.Capture.Amount
+	}
+
+	t.Status = updateTransaction.Status
+	t.UpdatedAt = now
+	t.Version++
+
+	return nil
+}
+
+func (c storeConfig) checkVersion(stored *gateway.TransactionStatus, updateTransaction UpdateTransaction) error {
+	if updateTransaction.ExpectedVersion == 0 || stored.Version == updateTransaction.ExpectedVersion {
+		return nil
+	}
+
+	return c.fail(gateway.KindConflict, "%w: expected version %d, found %d", gateway.ErrVersionConflict, updateTransaction.ExpectedVersion, stored.Version)
+}
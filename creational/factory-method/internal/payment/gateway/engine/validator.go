@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"factory-method/internal/payment/card"
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+	"fmt"
+)
+
+type ValidationRule func(gateway.PaymentDetails) error
+
+type RuleValidator struct {
+	rules []ValidationRule
+}
+
+func NewValidator(rules ...ValidationRule) *RuleValidator {
+	return &RuleValidator{rules: rules}
+}
+
+func DefaultValidationRules() []ValidationRule {
+	return []ValidationRule{
+		PositiveAmount,
+		KnownCurrency,
+	}
+}
+
+func (v *RuleValidator) Validate(details gateway.PaymentDetails) error {
+	errs := &gateway.ValidationError{}
+
+	for _, rule := range v.rules {
+		err := rule(details)
+		if err == nil {
+			continue
+		}
+
+		if !errs.Merge(err) {
+			return err
+		}
+	}
+
+	return errs.ErrOrNil()
+}
+
+func PositiveAmount(details gateway.PaymentDetails) error {
+	if !details.Amount.IsPositive() {
+		return &gateway.FieldError{Field: "amount", Code: gateway.CodeInvalid, Message: "amount must be greater than zero"}
+	}
+	return nil
+}
+
+func KnownCurrency(details gateway.PaymentDetails) error {
+	if details.Amount.Currency() == "" {
+		return &gateway.FieldError{Field: "currency", Code: gateway.CodeRequired, Message: "currency is required"}
+	}
+	if !money.IsKnownCurrency(details.Amount.Currency()) {
+		return &gateway.FieldError{Field: "currency", Code: gateway.CodeUnsupported, Message: fmt.Sprintf("unsupported currency %q", details.Amount.Currency())}
+	}
+	return nil
+}
+
+func MetadataLimits(details gateway.PaymentDetails) error {
+	return validateReferences(details.MerchantReference, details.Metadata)
+}
+
+func validateReferences(merchantReference string, metadata gateway.Metadata) error {
+	errs := &gateway.ValidationError{}
+	errs.Merge(gateway.ValidateMerchantReference(merchantReference))
+	errs.Merge(metadata.Validate())
+	return errs.ErrOrNil()
+}
+
+func CardRule(validator *card.Validator) ValidationRule {
+	return func(details gateway.PaymentDetails) error {
+		return validator.Validate(card.Card{
+			Number: details.CardNumber,
+			Holder: details.CardHolder,
+			Expiry: details.ExpiryDate,
+			CVV:    details.CVV,
+		})
+	}
+}
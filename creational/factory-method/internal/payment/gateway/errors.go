@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorKind string
+
+const (
+	KindDeclined       ErrorKind = "declined"
+	KindInvalidRequest ErrorKind = "invalid_request"
+	KindNotFound       ErrorKind = "not_found"
+	KindTransient      ErrorKind = "transient"
+	KindConflict       ErrorKind = "conflict"
+)
+
+var (
+	ErrDeclined       = errors.New("payment declined")
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrNotFound       = errors.New("not found")
+	ErrTransient      = errors.New("transient failure")
+	ErrConflict       = errors.New("conflict")
+)
+
+var kindErrors = map[ErrorKind]error{
+	KindDeclined:       ErrDeclined,
+	KindInvalidRequest: ErrInvalidRequest,
+	KindNotFound:       ErrNotFound,
+	KindTransient:      ErrTransient,
+	KindConflict:       ErrConflict,
+}
+
+var ErrVersionConflict error = NewError(KindConflict, "", "transaction was modified concurrently")
+
+type DeclineReason string
+
+const (
+	DeclineAuthenticationFailed DeclineReason = "authentication_failed"
+)
+
+type Error struct {
+	Kind        ErrorKind
+	Provider    string
+	DeclineCode string
+	Message     string
+	Err         error
+}
+
+func NewError(kind ErrorKind, provider string, message string) *Error {
+	return &Error{Kind: kind, Provider: provider, Message: message}
+}
+
+func Errorf(kind ErrorKind, provider string, format string, args ...any) *Error {
+	err := fmt.Errorf(format, args...)
+
+	return &Error{
+		Kind:     kind,
+		Provider: provider,
+		Message:  err.Error(),
+		Err:      wrappedError(err),
+	}
+}
+
+func wrappedError(err error) error {
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		return errors.Join(multi.Unwrap()...)
+	}
+	return errors.Unwrap(err)
+}
+
+func Declined(provider string, code string, message string) *Error {
+	return &Error{
+		Kind:        KindDeclined,
+		Provider:    provider,
+		DeclineCode: code,
+		Message:     message,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Provider == "" {
+		return e.Message
+	}
+	return e.Provider + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return kindErrors[e.Kind] == target
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestErrorfKeepsEveryWrappedError(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   *Error
+		wraps []error
+	}{
+		{"none", Errorf(KindTransient, "stripe", "connection reset"), nil},
+		{"single", Errorf(KindTransient, "stripe", "read: %w", io.ErrUnexpectedEOF), []error{io.ErrUnexpectedEOF}},
+		{
+			"multiple",
+			Errorf(KindTransient, "stripe", "read: %w after %w", io.ErrUnexpectedEOF, context.DeadlineExceeded),
+			[]error{io.ErrUnexpectedEOF, context.DeadlineExceeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, ErrTransient) {
+				t.Fatalf("%v does not match ErrTransient", tt.err)
+			}
+			for _, wrapped := range tt.wraps {
+				if !errors.Is(tt.err, wrapped) {
+					t.Fatalf("%v does not wrap %v", tt.err, wrapped)
+				}
+			}
+			if len(tt.wraps) == 0 && tt.err.Unwrap() != nil {
+				t.Fatalf("Unwrap = %v, want nil without %%w", tt.err.Unwrap())
+			}
+		})
+	}
+
+	err := Errorf(KindTransient, "stripe", "read: %w after %w", io.ErrUnexpectedEOF, context.DeadlineExceeded)
+	if got, want := err.Error(), "stripe: read: unexpected EOF after context deadline exceeded"; got != want {
+		t.Fatalf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestErrorMatchesOnlyItsKind(t *testing.T) {
+	for kind, sentinel := range kindErrors {
+		err := NewError(kind, "paypal", "boom")
+
+		for other, otherSentinel := range kindErrors {
+			if got := errors.Is(err, otherSentinel); got != (other == kind) {
+				t.Fatalf("errors.Is(%s error, %v) = %v", kind, otherSentinel, got)
+			}
+		}
+		if !errors.Is(err, sentinel) {
+			t.Fatalf("%s error does not match %v", kind, sentinel)
+		}
+	}
+}
+
+func TestDeclinedCarriesDeclineCode(t *testing.T) {
+	err := Declined("stripe", "insufficient_funds", "card has insufficient funds")
+
+	var gatewayErr *Error
+	if !errors.As(error(err), &gatewayErr) {
+		t.Fatal("Declined does not return a *Error")
+	}
+	if gatewayErr.Kind != KindDeclined || gatewayErr.DeclineCode != "insufficient_funds" {
+		t.Fatalf("kind %s with code %q, want declined with insufficient_funds", gatewayErr.Kind, gatewayErr.DeclineCode)
+	}
+	if !errors.Is(err, ErrDeclined) {
+		t.Fatal("Declined does not match ErrDeclined")
+	}
+}
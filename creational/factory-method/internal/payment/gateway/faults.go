@@ -0,0 +1,149 @@
+package gateway
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type Operation string
+
+const (
+	OpSave                 Operation = "save"
+	OpGet                  Operation = "get"
+	OpUpdate               Operation = "update"
+	OpFindByIdempotencyKey Operation = "find_by_idempotency_key"
+	OpGetHistory           Operation = "get_history"
+	OpList                 Operation = "list"
+	OpPaymentOutcome       Operation = "payment_outcome"
+)
+
+type LatencyDistribution func(rng *rand.Rand) time.Duration
+
+func NoLatency() LatencyDistribution {
+	return func(*rand.Rand) time.Duration {
+		return 0
+	}
+}
+
+func FixedLatency(latency time.Duration) LatencyDistribution {
+	return func(*rand.Rand) time.Duration {
+		return latency
+	}
+}
+
+func UniformLatency(min, max time.Duration) LatencyDistribution {
+	if max < min {
+		min, max = max, min
+	}
+
+	return func(rng *rand.Rand) time.Duration {
+		if max == min {
+			return min
+		}
+		return min + time.Duration(rng.Int63n(int64(max-min)))
+	}
+}
+
+type FaultConfig struct {
+	Disabled                  bool
+	DefaultFailureProbability float64
+	FailureProbability        map[Operation]float64
+	Latency                   LatencyDistribution
+	Seed                      int64
+	Script                    map[Operation][]bool
+}
+
+func DefaultFaultConfig() FaultConfig {
+	return FaultConfig{
+		DefaultFailureProbability: 0.5,
+		Latency:                   UniformLatency(500*time.Millisecond, 2000*time.Millisecond),
+	}
+}
+
+func DisabledFaultConfig() FaultConfig {
+	return FaultConfig{Disabled: true}
+}
+
+type FaultInjector struct {
+	config  FaultConfig
+	rng     *rand.Rand
+	scripts map[Operation][]bool
+	mu      sync.Mutex
+}
+
+func NewFaultInjector(config FaultConfig) *FaultInjector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	if config.Latency == nil {
+		config.Latency = NoLatency()
+	}
+
+	scripts := make(map[Operation][]bool, len(config.Script))
+	for op, script := range config.Script {
+		scripts[op] = append([]bool(nil), script...)
+	}
+
+	return &FaultInjector{
+		config:  config,
+		rng:     rand.New(rand.NewSource(seed)),
+		scripts: scripts,
+	}
+}
+
+func (f *FaultInjector) ShouldFail(op Operation) bool {
+	if f == nil || f.config.Disabled {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if script := f.scripts[op]; len(script) > 0 {
+		f.scripts[op] = script[1:]
+		return script[0]
+	}
+
+	probability, ok := f.config.FailureProbability[op]
+	if !ok {
+		probability = f.config.DefaultFailureProbability
+	}
+
+	if probability <= 0 {
+		return false
+	}
+
+	return f.rng.Float64() < probability
+}
+
+func (f *FaultInjector) Latency() time.Duration {
+	if f == nil || f.config.Disabled {
+		return 0
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.config.Latency(f.rng)
+}
+
+func (f *FaultInjector) Wait(ctx context.Context) error {
+	latency := f.Latency()
+	if latency <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func faultSequence(f *FaultInjector, op Operation, n int) []bool {
+	outcomes := make([]bool, n)
+	for i := range outcomes {
+		outcomes[i] = f.ShouldFail(op)
+	}
+	return outcomes
+}
+
+func TestFaultInjectorIsDeterministicForSeed(t *testing.T) {
+	config := FaultConfig{
+		DefaultFailureProbability: 0.5,
+		Latency:                   UniformLatency(time.Millisecond, time.Second),
+		Seed:                      42,
+	}
+
+	first, second := NewFaultInjector(config), NewFaultInjector(config)
+
+	outcomes := faultSequence(first, OpSave, 64)
+	if got := faultSequence(second, OpSave, 64); !slices.Equal(got, outcomes) {
+		t.Fatalf("same seed produced different faults:\n%v\n%v", outcomes, got)
+	}
+	if !slices.Contains(outcomes, true) || !slices.Contains(outcomes, false) {
+		t.Fatalf("failure probability 0.5 produced %v", outcomes)
+	}
+
+	for range 16 {
+		if a, b := first.Latency(), second.Latency(); a != b {
+			t.Fatalf("same seed produced latencies %s and %s", a, b)
+		}
+	}
+
+	config.Seed = 43
+	if got := faultSequence(NewFaultInjector(config), OpSave, 64); slices.Equal(got, outcomes) {
+		t.Fatal("different seeds produced the same faults")
+	}
+}
+
+func TestFaultInjectorProbabilities(t *testing.T) {
+	f := NewFaultInjector(FaultConfig{
+		DefaultFailureProbability: 1,
+		FailureProbability:        map[Operation]float64{OpGet: 0},
+		Seed:                      1,
+	})
+
+	if slices.Contains(faultSequence(f, OpGet, 32), true) {
+		t.Fatal("operation with failure probability 0 failed")
+	}
+	if slices.Contains(faultSequence(f, OpUpdate, 32), false) {
+		t.Fatal("operation falling back to default probability 1 succeeded")
+	}
+
+	var disabled *FaultInjector
+	if disabled.ShouldFail(OpSave) || disabled.Latency() != 0 {
+		t.Fatal("nil injector injected a fault")
+	}
+	if f := NewFaultInjector(DisabledFaultConfig()); f.ShouldFail(OpSave) || f.Latency() != 0 {
+		t.Fatal("disabled injector injected a fault")
+	}
+}
+
+func TestFaultInjectorConsumesScriptPerOperation(t *testing.T) {
+	script := []bool{true, false, true}
+
+	f := NewFaultInjector(FaultConfig{
+		DefaultFailureProbability: 0,
+		Script:                    map[Operation][]bool{OpSave: script, OpGet: {true}},
+	})
+	script[0] = false
+
+	if got := faultSequence(f, OpGet, 3); !slices.Equal(got, []bool{true, false, false}) {
+		t.Fatalf("get faults = %v, want the script then the default probability", got)
+	}
+	if got := faultSequence(f, OpSave, 5); !slices.Equal(got, []bool{true, false, true, false, false}) {
+		t.Fatalf("save faults = %v, want the script as configured then the default probability", got)
+	}
+	if f.ShouldFail(OpUpdate) {
+		t.Fatal("unscripted operation failed with probability 0")
+	}
+}
+
+func TestFaultInjectorWaitReturnsOnCancel(t *testing.T) {
+	f := NewFaultInjector(FaultConfig{Latency: FixedLatency(time.Hour)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- f.Wait(ctx) }()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Wait = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Wait did not return after the context was canceled")
+	}
+
+	if err := NewFaultInjector(DisabledFaultConfig()).Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait without latency on canceled context = %v, want context.Canceled", err)
+	}
+	if err := NewFaultInjector(FaultConfig{Latency: FixedLatency(time.Millisecond)}).Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
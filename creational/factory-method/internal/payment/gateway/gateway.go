@@ -3,32 +3,98 @@ package gateway
 import (
 	"context"
 	"time"
+
+	"factory-method/pkg/money"
 )
 
 type PaymentDetails struct {
-	Amount      float64
-	Currency    string
-	CardNumber  string
-	CardHolder  string
-	ExpiryDate  string
-	CVV         string
-	Description string
+	IdempotencyKey    string
+	Amount            money.Money
+	CardToken         string
+	CardNumber        string
+	CardHolder        string
+	ExpiryDate        string
+	CVV               string
+	Description       string
+	MerchantReference string
+	Metadata          Metadata
 }
 
 type RefundDetails struct {
+	IdempotencyKey    string
+	TransactionID     string
+	Amount            money.Money
+	Reason            string
+	MerchantReference string
+	Metadata          Metadata
+}
+
+type CaptureDetails struct {
+	IdempotencyKey string
+	TransactionID  string
+	Amount         money.Money
+}
+
+type VoidDetails struct {
 	TransactionID string
-	Amount        float64
 	Reason        string
 }
 
+type RefundRecord struct {
+	RefundID          string
+	Amount            money.Money
+	Reason            string
+	MerchantReference string
+	Metadata          Metadata
+	CreatedAt         time.Time
+}
+
 type TransactionStatus struct {
-	TransactionID string
-	Status        TransactionStatusType
-	Amount        float64
-	Currency      string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	ErrorMessage  string
+	TransactionID          string
+	Provider               string
+	Status                 TransactionStatusType
+	Amount                 money.Money
+	CapturedAmount         money.Money
+	RefundedAmount         money.Money
+	Fee                    money.Money
+	Refunds                []RefundRecord
+	AuthorizationExpiresAt time.Time
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	ErrorMessage           string
+	MerchantReference      string
+	Metadata               Metadata
+	Version                int64
+}
+
+func (t *TransactionStatus) Clone() *TransactionStatus {
+	if t == nil {
+		return nil
+	}
+
+	clone := *t
+	clone.Metadata = t.Metadata.Clone()
+	if t.Refunds != nil {
+		clone.Refunds = make([]RefundRecord, len(t.Refunds))
+		for i, refund := range t.Refunds {
+			refund.Metadata = refund.Metadata.Clone()
+			clone.Refunds[i] = refund
+		}
+	}
+
+	return &clone
+}
+
+func (t *TransactionStatus) RefundableAmount() (money.Money, error) {
+	return t.CapturedAmount.Sub(t.RefundedAmount)
+}
+
+func (t *TransactionStatus) AuthorizationExpired(now time.Time) bool {
+	return t.Status == StatusAuthorized && !t.AuthorizationExpiresAt.IsZero() && !now.Before(t.AuthorizationExpiresAt)
+}
+
+func (t *TransactionStatus) IsRefundable() bool {
+	return t.Status == StatusCompleted || t.Status == StatusPartiallyRefunded
 }
 
 type TransactionStatusType string
@@ -38,10 +104,22 @@ const (
 	StatusCompleted TransactionStatusType = "completed"
 	StatusFailed    TransactionStatusType = "failed"
 	StatusRefund    TransactionStatusType = "refund"
+
+	StatusPartiallyRefunded TransactionStatusType = "partially_refunded"
+	StatusAuthorized        TransactionStatusType = "authorized"
+	StatusVoided            TransactionStatusType = "voided"
 )
 
+const DefaultAuthorizationTTL = 7 * 24 * time.Hour
+
 type PaymentGateway interface {
 	ProcessPayment(ctx context.Context, details PaymentDetails) (*TransactionStatus, error)
+	Authorize(ctx context.Context, details PaymentDetails) (*TransactionStatus, error)
+	Capture(ctx context.Context, details CaptureDetails) (*TransactionStatus, error)
+	Void(ctx context.Context, details VoidDetails) (*TransactionStatus, error)
 	Refund(ctx context.Context, details RefundDetails) (*TransactionStatus, error)
 	GetStatus(ctx context.Context, transactionID string) (*TransactionStatus, error)
+	GetHistory(ctx context.Context, transactionID string) ([]TransactionEvent, error)
+	List(ctx context.Context, query ListQuery) (*TransactionPage, error)
+	FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*TransactionStatus, error)
 }
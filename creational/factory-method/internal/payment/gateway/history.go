@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"factory-method/pkg/money"
+)
+
+const SystemActor = "system"
+
+type EventType string
+
+const (
+	EventCreated         EventType = "created"
+	EventStatusChanged   EventType = "status_changed"
+	EventRefundRequested EventType = "refund_requested"
+	EventError           EventType = "error"
+)
+
+type TransactionEvent struct {
+	EventID       string
+	TransactionID string
+	Type          EventType
+	FromStatus    TransactionStatusType
+	ToStatus      TransactionStatusType
+	Amount        money.Money
+	Actor         string
+	Reason        string
+	CreatedAt     time.Time
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
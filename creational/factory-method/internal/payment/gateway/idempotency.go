@@ -0,0 +1,223 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultIdempotencyRetention = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyNotFound         = errors.New("idempotency key not found")
+	ErrIdempotencyKeyReused     error = NewError(KindConflict, "", "idempotency key reused with a different request payload")
+	ErrIdempotencyKeyInProgress error = NewError(KindConflict, "", "request with the same idempotency key is still in progress")
+)
+
+type idempotencyRecord struct {
+	fingerprint string
+	result      *TransactionStatus
+	expiresAt   time.Time
+}
+
+type IdempotencyKeys struct {
+	retention time.Duration
+	now       func() time.Time
+	nextSweep time.Time
+	records   map[string]*idempotencyRecord
+	mu        sync.Mutex
+}
+
+func NewIdempotencyKeys(retention time.Duration) *IdempotencyKeys {
+	if retention <= 0 {
+		retention = DefaultIdempotencyRetention
+	}
+
+	return &IdempotencyKeys{
+		retention: retention,
+		now:       func() time.Time { return time.Now().UTC() },
+		records:   make(map[string]*idempotencyRecord),
+	}
+}
+
+func (k *IdempotencyKeys) Lookup(key, fingerprint string) (*TransactionStatus, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	record, ok := k.activeRecord(key)
+	if !ok {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+
+	return record.replay(fingerprint)
+}
+
+func (k *IdempotencyKeys) Begin(key, fingerprint string) (*TransactionStatus, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.sweep()
+
+	if record, ok := k.activeRecord(key); ok {
+		return record.replay(fingerprint)
+	}
+
+	k.records[key] = &idempotencyRecord{
+		fingerprint: fingerprint,
+		expiresAt:   k.now().Add(k.retention),
+	}
+
+	return nil, nil
+}
+
+func (k *IdempotencyKeys) Complete(key string, result *TransactionStatus) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	record, ok := k.records[key]
+	if !ok {
+		return
+	}
+
+	record.result = result.Clone()
+	record.expiresAt = k.now().Add(k.retention)
+}
+
+func (k *IdempotencyKeys) Remember(key, fingerprint string, result *TransactionStatus, recordedAt time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	expiresAt := recordedAt.Add(k.retention)
+	if !k.now().Before(expiresAt) {
+		return
+	}
+
+	k.records[key] = &idempotencyRecord{
+		fingerprint: fingerprint,
+		result:      result.Clone(),
+		expiresAt:   expiresAt,
+	}
+}
+
+func (k *IdempotencyKeys) Abort(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if record, ok := k.records[key]; ok && record.result == nil {
+		delete(k.records, key)
+	}
+}
+
+func (k *IdempotencyKeys) activeRecord(key string) (*idempotencyRecord, bool) {
+	record, ok := k.records[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !k.now().Before(record.expiresAt) {
+		delete(k.records, key)
+		return nil, false
+	}
+
+	return record, true
+}
+
+func (k *IdempotencyKeys) sweep() {
+	now := k.now()
+	if now.Before(k.nextSweep) {
+		return
+	}
+
+	for key, record := range k.records {
+		if !now.Before(record.expiresAt) {
+			delete(k.records, key)
+		}
+	}
+
+	k.nextSweep = now.Add(k.retention / 2)
+}
+
+func (r *idempotencyRecord) replay(fingerprint string) (*TransactionStatus, error) {
+	if r.fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	if r.result == nil {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return r.result.Clone(), nil
+}
+
+func PaymentFingerprint(details PaymentDetails) string {
+	return paymentFingerprint("payment", details)
+}
+
+func AuthorizationFingerprint(details PaymentDetails) string {
+	return paymentFingerprint("authorization", details)
+}
+
+func CaptureFingerprint(details CaptureDetails) string {
+	return fingerprint(
+		"capture",
+		details.TransactionID,
+		strconv.FormatInt(details.Amount.Amount(), 10),
+		details.Amount.Currency(),
+	)
+}
+
+func paymentFingerprint(kind string, details PaymentDetails) string {
+	parts := []string{
+		kind,
+		strconv.FormatInt(details.Amount.Amount(), 10),
+		details.Amount.Currency(),
+		details.CardToken,
+		cardFingerprint(details.CardNumber),
+		details.CardHolder,
+		details.ExpiryDate,
+		details.Description,
+	}
+
+	return fingerprint(withReferences(parts, details.MerchantReference, details.Metadata)...)
+}
+
+func cardFingerprint(number string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, number)
+
+	return digits[max(0, len(digits)-4):]
+}
+
+func RefundFingerprint(details RefundDetails) string {
+	parts := []string{
+		"refund",
+		details.TransactionID,
+		strconv.FormatInt(details.Amount.Amount(), 10),
+		details.Amount.Currency(),
+		details.Reason,
+	}
+
+	return fingerprint(withReferences(parts, details.MerchantReference, details.Metadata)...)
+}
+
+func withReferences(parts []string, merchantReference string, metadata Metadata) []string {
+	if merchantReference == "" && len(metadata) == 0 {
+		return parts
+	}
+
+	parts = append(parts, merchantReference)
+	return append(parts, metadata.fingerprintParts()...)
+}
+
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
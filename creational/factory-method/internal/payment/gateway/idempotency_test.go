@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"factory-method/pkg/money"
+)
+
+func testPaymentDetails(number string) PaymentDetails {
+	return PaymentDetails{
+		IdempotencyKey: "order-1",
+		Amount:         money.New(1000, "USD"),
+		CardNumber:     number,
+		CardHolder:     "Jane Doe",
+		ExpiryDate:     "12/40",
+		CVV:            "123",
+	}
+}
+
+func TestPaymentFingerprintOnlyUsesCardLastFour(t *testing.T) {
+	visa := PaymentFingerprint(testPaymentDetails("4242424242424242"))
+
+	if got := PaymentFingerprint(testPaymentDetails("4242 4242 4242 4242")); got != visa {
+		t.Fatal("formatting the card number changed the fingerprint")
+	}
+	if got := PaymentFingerprint(testPaymentDetails("5555555555554444")); got == visa {
+		t.Fatal("cards with different last four digits share a fingerprint")
+	}
+
+	if want := fingerprint("payment", "1000", "USD", "", "4242", "Jane Doe", "12/40", ""); visa != want {
+		t.Fatal("fingerprint covers more of the card number than its last four digits")
+	}
+}
+
+func newTestKeys(retention time.Duration) (*IdempotencyKeys, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	keys := NewIdempotencyKeys(retention)
+	keys.now = func() time.Time { return now }
+
+	return keys, &now
+}
+
+func TestIdempotencyKeysReplayCompletedResult(t *testing.T) {
+	keys, _ := newTestKeys(time.Hour)
+
+	if _, err := keys.Lookup("order-1", "fp"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Lookup before Begin = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+
+	if result, err := keys.Begin("order-1", "fp"); result != nil || err != nil {
+		t.Fatalf("Begin = %v, %v, want nil, nil", result, err)
+	}
+	if _, err := keys.Begin("order-1", "fp"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("Begin while in progress = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+
+	keys.Complete("order-1", &TransactionStatus{TransactionID: "txn-1", Status: StatusCompleted})
+
+	for _, replay := range []func(string, string) (*TransactionStatus, error){keys.Lookup, keys.Begin} {
+		result, err := replay("order-1", "fp")
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if result.TransactionID != "txn-1" || result.Status != StatusCompleted {
+			t.Fatalf("replayed %s (%s), want txn-1 (completed)", result.TransactionID, result.Status)
+		}
+		result.Status = StatusFailed
+	}
+
+	if result, _ := keys.Lookup("order-1", "fp"); result.Status != StatusCompleted {
+		t.Fatalf("mutating a replayed result changed the stored one to %s", result.Status)
+	}
+}
+
+func TestIdempotencyKeysRejectDifferentFingerprint(t *testing.T) {
+	keys, _ := newTestKeys(time.Hour)
+
+	if _, err := keys.Begin("order-1", "fp"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := keys.Begin("order-1", "other"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin in progress with another fingerprint = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	keys.Complete("order-1", &TransactionStatus{TransactionID: "txn-1"})
+
+	if _, err := keys.Lookup("order-1", "other"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Lookup with another fingerprint = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if _, err := keys.Begin("order-1", "other"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("Begin with another fingerprint = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestIdempotencyKeysExpireAfterRetention(t *testing.T) {
+	keys, now := newTestKeys(time.Hour)
+
+	if _, err := keys.Begin("order-1", "fp"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	keys.Complete("order-1", &TransactionStatus{TransactionID: "txn-1"})
+
+	*now = now.Add(time.Hour - time.Nanosecond)
+	if _, err := keys.Lookup("order-1", "fp"); err != nil {
+		t.Fatalf("Lookup just before expiry: %v", err)
+	}
+
+	*now = now.Add(time.Nanosecond)
+	if _, err := keys.Lookup("order-1", "fp"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Lookup at expiry = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+	if result, err := keys.Begin("order-1", "other"); result != nil || err != nil {
+		t.Fatalf("Begin on expired key = %v, %v, want nil, nil", result, err)
+	}
+
+	keys.Remember("order-2", "fp", &TransactionStatus{TransactionID: "txn-2"}, now.Add(-time.Hour))
+	if _, err := keys.Lookup("order-2", "fp"); !errors.Is(err, ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Lookup of key remembered past retention = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+
+	keys.Remember("order-3", "fp", &TransactionStatus{TransactionID: "txn-3"}, now.Add(-time.Minute))
+	if result, err := keys.Lookup("order-3", "fp"); err != nil || result.TransactionID != "txn-3" {
+		t.Fatalf("Lookup of remembered key = %v, %v, want txn-3", result, err)
+	}
+}
+
+func TestIdempotencyKeysConcurrentBeginAdmitsOne(t *testing.T) {
+	keys := NewIdempotencyKeys(time.Hour)
+
+	const callers = 32
+
+	for round := range 3 {
+		var (
+			wg         sync.WaitGroup
+			mu         sync.Mutex
+			admitted   int
+			inProgress int
+		)
+
+		for range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, err := keys.Begin("order-1", "fp")
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch {
+				case err == nil:
+					admitted++
+				case errors.Is(err, ErrIdempotencyKeyInProgress):
+					inProgress++
+				default:
+					t.Errorf("Begin: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if admitted != 1 || inProgress != callers-1 {
+			t.Fatalf("round %d: %d callers admitted and %d in progress, want 1 and %d", round, admitted, inProgress, callers-1)
+		}
+
+		keys.Abort("order-1")
+	}
+
+	if _, err := keys.Begin("order-1", "fp"); err != nil {
+		t.Fatalf("Begin after Abort: %v", err)
+	}
+	keys.Complete("order-1", &TransactionStatus{TransactionID: "txn-1"})
+	keys.Abort("order-1")
+
+	if result, err := keys.Lookup("order-1", "fp"); err != nil || result.TransactionID != "txn-1" {
+		t.Fatalf("Lookup after Abort of completed key = %v, %v, want txn-1", result, err)
+	}
+}
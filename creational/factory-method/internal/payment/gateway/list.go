@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"slices"
+	"time"
+)
+
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByAmount    SortField = "amount"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+type ListFilter struct {
+	Statuses          []TransactionStatusType
+	Currency          string
+	MinAmount         *int64
+	MaxAmount         *int64
+	CreatedAfter      time.Time
+	CreatedBefore     time.Time
+	Provider          string
+	MerchantReference string
+}
+
+type ListQuery struct {
+	Filter ListFilter
+	SortBy SortField
+	Order  SortOrder
+	Limit  int
+	Cursor string
+	after  *cursor
+}
+
+type TransactionPage struct {
+	Transactions []*TransactionStatus
+	NextCursor   string
+}
+
+type cursor struct {
+	SortBy        SortField `json:"s"`
+	Order         SortOrder `json:"o"`
+	Key           int64     `json:"k"`
+	TransactionID string    `json:"id"`
+}
+
+func (q ListQuery) Normalize() (ListQuery, error) {
+	errs := &ValidationError{}
+
+	if q.SortBy == "" {
+		q.SortBy = SortByCreatedAt
+	}
+	if q.SortBy != SortByCreatedAt && q.SortBy != SortByAmount {
+		errs.Add("sort", CodeUnsupported, "sort must be one of created_at, amount")
+	}
+
+	if q.Order == "" {
+		q.Order = SortDescending
+	}
+	if q.Order != SortAscending && q.Order != SortDescending {
+		errs.Add("order", CodeUnsupported, "order must be one of asc, desc")
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultListLimit
+	case q.Limit < 0 || q.Limit > MaxListLimit:
+		errs.Add("limit", CodeInvalid, "limit must be between 1 and 200")
+	}
+
+	f := q.Filter
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MinAmount > *f.MaxAmount {
+		errs.Add("min_amount", CodeInvalid, "min_amount must not exceed max_amount")
+	}
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedAfter.Before(f.CreatedBefore) {
+		errs.Add("created_after", CodeInvalid, "created_after must be before created_before")
+	}
+
+	q.after = nil
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		switch {
+		case err != nil:
+			errs.Add("cursor", CodeInvalidFormat, "cursor is malformed")
+		case after.SortBy != q.SortBy || after.Order != q.Order:
+			errs.Add("cursor", CodeInvalid, "cursor was issued for a different sort order")
+		default:
+			q.after = &after
+		}
+	}
+
+	if err := errs.ErrOrNil(); err != nil {
+		return ListQuery{}, err
+	}
+
+	return q, nil
+}
+
+func (q ListQuery) Descending() bool {
+	return q.Order == SortDescending
+}
+
+func (q ListQuery) SortKey(t *TransactionStatus) int64 {
+	if q.SortBy == SortByAmount {
+		return t.Amount.Amount()
+	}
+	return t.CreatedAt.UnixNano()
+}
+
+func (q ListQuery) KeyRange() (int64, int64) {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+
+	switch q.SortBy {
+	case SortByAmount:
+		if q.Filter.MinAmount != nil {
+			lo = *q.Filter.MinAmount
+		}
+		if q.Filter.MaxAmount != nil {
+			hi = *q.Filter.MaxAmount
+		}
+	default:
+		if !q.Filter.CreatedAfter.IsZero() {
+			lo = q.Filter.CreatedAfter.UnixNano()
+		}
+		if !q.Filter.CreatedBefore.IsZero() {
+			hi = q.Filter.CreatedBefore.UnixNano() - 1
+		}
+	}
+
+	return lo, hi
+}
+
+func (q ListQuery) Position() (int64, string, bool) {
+	if q.after == nil {
+		return 0, "", false
+	}
+	return q.after.Key, q.after.TransactionID, true
+}
+
+func (q ListQuery) Compare(a, b *TransactionStatus) int {
+	order := cmp.Or(
+		cmp.Compare(q.SortKey(a), q.SortKey(b)),
+		cmp.Compare(a.TransactionID, b.TransactionID),
+	)
+	if q.Descending() {
+		return -order
+	}
+	return order
+}
+
+func (q ListQuery) Matches(t *TransactionStatus) bool {
+	f := q.Filter
+
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, t.Status) {
+		return false
+	}
+	if f.Currency != "" && t.Amount.Currency() != f.Currency {
+		return false
+	}
+	if f.Provider != "" && t.Provider != f.Provider {
+		return false
+	}
+	if f.MerchantReference != "" && t.MerchantReference != f.MerchantReference {
+		return false
+	}
+	if f.MinAmount != nil && t.Amount.Amount() < *f.MinAmount {
+		return false
+	}
+	if f.MaxAmount != nil && t.Amount.Amount() > *f.MaxAmount {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && t.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !t.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+
+	return q.After(t)
+}
+
+func (q ListQuery) After(t *TransactionStatus) bool {
+	key, id, ok := q.Position()
+	if !ok {
+		return true
+	}
+
+	order := cmp.Or(cmp.Compare(q.SortKey(t), key), cmp.Compare(t.TransactionID, id))
+	if q.Descending() {
+		return order < 0
+	}
+	return order > 0
+}
+
+func (q ListQuery) Page(transactions []*TransactionStatus, more bool) *TransactionPage {
+	page := &TransactionPage{Transactions: transactions}
+
+	if len(transactions) > q.Limit {
+		page.Transactions = transactions[:q.Limit]
+		more = true
+	}
+
+	if more && len(page.Transactions) > 0 {
+		last := page.Transactions[len(page.Transactions)-1]
+		page.NextCursor = encodeCursor(cursor{
+			SortBy:        q.SortBy,
+			Order:         q.Order,
+			Key:           q.SortKey(last),
+			TransactionID: last.TransactionID,
+		})
+	}
+
+	if page.Transactions == nil {
+		page.Transactions = []*TransactionStatus{}
+	}
+
+	return page
+}
+
+func encodeCursor(c cursor) string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor{}, err
+	}
+
+	if err := json.Unmarshal(decoded, &c); err != nil {
+		return cursor{}, err
+	}
+
+	return c, nil
+}
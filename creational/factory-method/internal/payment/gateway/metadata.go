@@ -0,0 +1,70 @@
+package gateway
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	MaxMetadataKeys            = 20
+	MaxMetadataKeyLength       = 40
+	MaxMetadataValueLength     = 500
+	MaxMerchantReferenceLength = 128
+)
+
+type Metadata map[string]string
+
+func (m Metadata) Clone() Metadata {
+	if m == nil {
+		return nil
+	}
+	return maps.Clone(m)
+}
+
+func (m Metadata) Validate() error {
+	errs := &ValidationError{}
+
+	if len(m) > MaxMetadataKeys {
+		errs.Add("metadata", CodeInvalidLength, fmt.Sprintf("metadata must not have more than %d keys", MaxMetadataKeys))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		field := "metadata." + key
+
+		switch {
+		case strings.TrimSpace(key) == "":
+			errs.Add("metadata", CodeInvalid, "metadata keys must not be blank")
+		case len(key) > MaxMetadataKeyLength:
+			errs.Add(field, CodeInvalidLength, fmt.Sprintf("metadata keys must not exceed %d bytes", MaxMetadataKeyLength))
+		case strings.IndexFunc(key, unicode.IsControl) >= 0:
+			errs.Add(field, CodeInvalidFormat, "metadata keys must not contain control characters")
+		}
+
+		if len(m[key]) > MaxMetadataValueLength {
+			errs.Add(field, CodeInvalidLength, fmt.Sprintf("metadata values must not exceed %d bytes", MaxMetadataValueLength))
+		}
+	}
+
+	return errs.ErrOrNil()
+}
+
+func ValidateMerchantReference(reference string) error {
+	if len(reference) > MaxMerchantReferenceLength {
+		return &FieldError{Field: "merchant_reference", Code: CodeInvalidLength, Message: fmt.Sprintf("merchant reference must not exceed %d bytes", MaxMerchantReferenceLength)}
+	}
+	if strings.IndexFunc(reference, unicode.IsControl) >= 0 {
+		return &FieldError{Field: "merchant_reference", Code: CodeInvalidFormat, Message: "merchant reference must not contain control characters"}
+	}
+	return nil
+}
+
+func (m Metadata) fingerprintParts() []string {
+	parts := make([]string, 0, len(m))
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		parts = append(parts, key+"\x1f"+m[key])
+	}
+	return parts
+}
@@ -2,122 +2,59 @@ package paypal
 
 import (
 	"context"
-	"errors"
+	"database/sql"
+
+	"factory-method/internal/payment/card"
 	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/sqlstore"
+	"factory-method/internal/payment/gateway/wal"
+	"factory-method/pkg/money"
 )
 
-type Validator interface {
-	Validate(gateway.PaymentDetails) error
-}
-
-type SaveTransaction struct {
-	Amount   float64
-	Currency string
-}
-
-type UpdateTransaction struct {
-	TransactionID string
-	Status        gateway.TransactionStatusType
-}
+const Name = "paypal"
 
-type TransactionStore interface {
-	Save(ctx context.Context, saveTransaction *SaveTransaction) (*gateway.TransactionStatus, error)
-	Get(ctx context.Context, id string) (*gateway.TransactionStatus, error)
-	Update(ctx context.Context, updateTransaction UpdateTransaction) (*gateway.TransactionStatus, error)
-}
-
-type Authenticator interface {
-	Authenticate(card string) bool
-}
-
-type PaypalPaymentGateway struct {
-	store         TransactionStore
-	validator     Validator
-	authenticator Authenticator
-}
-
-func NewPaypalPaymentGateway(
-	store TransactionStore,
-	validator Validator,
-	authenticator Authenticator,
-) *PaypalPaymentGateway {
-	return &PaypalPaymentGateway{
-		store:         store,
-		validator:     validator,
-		authenticator: authenticator,
+func Provider() engine.Provider {
+	return engine.Provider{
+		Name:            Name,
+		ValidationRules: engine.DefaultValidationRules(),
+		AcceptedBrands: []card.Brand{
+			card.Visa,
+			card.Mastercard,
+			card.Amex,
+			card.Discover,
+			card.Maestro,
+		},
+		Authenticator: engine.NewCardAllowlist(
+			"4111111111111111",
+			"5105105105105100",
+		),
+		Fee: engine.PercentageFee(349,
+			money.New(49, "USD"),
+			money.New(39, "EUR"),
+			money.New(30, "GBP"),
+			money.New(59, "CAD"),
+			money.New(59, "AUD"),
+			money.New(40, "JPY"),
+		),
+		DeclineCodes: map[gateway.DeclineReason]string{
+			gateway.DeclineAuthenticationFailed: "INSTRUMENT_DECLINED",
+		},
 	}
 }
 
-func (ppg *PaypalPaymentGateway) ProcessPayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	if err := ppg.validator.Validate(details); err != nil {
-		return nil, err
-	}
-
-	if !ppg.authenticator.Authenticate(details.CardNumber) {
-		return nil, errors.New("paypal: authentication failed: invalid card")
-	}
-
-	saveTransaction := &SaveTransaction{
-		Amount:   details.Amount,
-		Currency: details.Currency,
-	}
-
-	savedTransaction, err := ppg.store.Save(ctx, saveTransaction)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return savedTransaction, nil
+func NewPaypalPaymentGateway(store engine.TransactionStore, opts ...engine.GatewayOption) *engine.Gateway {
+	return engine.NewGateway(Provider(), store, opts...)
 }
 
-func (ppg *PaypalPaymentGateway) Refund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	transaction, err := ppg.store.Get(ctx, details.TransactionID)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if transaction.Status != gateway.StatusCompleted {
-		return nil, errors.New("paypal: cannot refund non-completed transaction")
-	}
-
-	if details.Amount > transaction.Amount {
-		return nil, errors.New("paypal: refund amount exceeds transaction amount")
-	}
-
-	updateTransaction := &UpdateTransaction{
-		TransactionID: details.TransactionID,
-		Status:        gateway.StatusRefund,
-	}
-
-	updatedTransaction, err := ppg.store.Update(ctx, *updateTransaction)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return updatedTransaction, nil
+func NewTransactionStore(opts ...engine.StoreOption) *engine.InMemoryTransactionStore {
+	return engine.NewInMemoryTransactionStore(Name, opts...)
 }
 
-func (ppg *PaypalPaymentGateway) GetStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	transaction, err := ppg.store.Get(ctx, transactionID)
-
-	if err != nil {
-		return nil, err
-	}
+func NewFileTransactionStore(dir string, walOptions wal.Options, opts ...engine.StoreOption) (*engine.FileTransactionStore, error) {
+	return engine.NewFileTransactionStore(Name, dir, walOptions, opts...)
+}
 
-	return transaction, nil
+func NewSQLTransactionStore(ctx context.Context, db *sql.DB, dialect sqlstore.Dialect, opts ...engine.StoreOption) (*engine.SQLTransactionStore, error) {
+	return engine.NewSQLTransactionStore(ctx, Name, db, dialect, opts...)
 }
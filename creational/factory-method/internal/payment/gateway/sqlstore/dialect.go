@@ -0,0 +1,76 @@
+package sqlstore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Dialect struct {
+	Name              string
+	NumberedParams    bool
+	SupportsForUpdate bool
+	InsertIgnore      bool
+}
+
+var (
+	SQLite   = Dialect{Name: "sqlite"}
+	MySQL    = Dialect{Name: "mysql", SupportsForUpdate: true, InsertIgnore: true}
+	Postgres = Dialect{Name: "postgres", NumberedParams: true, SupportsForUpdate: true}
+)
+
+var dialects = map[string]Dialect{
+	SQLite.Name:   SQLite,
+	"sqlite3":     SQLite,
+	MySQL.Name:    MySQL,
+	Postgres.Name: Postgres,
+	"pgx":         Postgres,
+}
+
+func DialectFor(driverName string) (Dialect, error) {
+	dialect, ok := dialects[strings.ToLower(driverName)]
+	if !ok {
+		return Dialect{}, fmt.Errorf("sqlstore: unsupported driver %q", driverName)
+	}
+
+	return dialect, nil
+}
+
+func (d Dialect) rebind(query string) string {
+	if !d.NumberedParams {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteByte('$')
+		b.WriteString(strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+func (d Dialect) forUpdate(query string) string {
+	if !d.SupportsForUpdate {
+		return query
+	}
+	return query + " FOR UPDATE"
+}
+
+func (d Dialect) insertIfAbsent(query string) string {
+	if d.InsertIgnore {
+		return "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT")
+	}
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+func placeholders(n int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
@@ -0,0 +1,43 @@
+package sqlstore
+
+import "testing"
+
+func TestDialectRewritesQueries(t *testing.T) {
+	const query = "SELECT a FROM t WHERE b = ? AND c = ?"
+
+	if got, want := Postgres.rebind(query), "SELECT a FROM t WHERE b = $1 AND c = $2"; got != want {
+		t.Fatalf("Postgres.rebind = %q, want %q", got, want)
+	}
+	if got := MySQL.rebind(query); got != query {
+		t.Fatalf("MySQL.rebind = %q, want unchanged", got)
+	}
+
+	if got, want := MySQL.forUpdate(query), query+" FOR UPDATE"; got != want {
+		t.Fatalf("MySQL.forUpdate = %q, want %q", got, want)
+	}
+	if got := SQLite.forUpdate(query); got != query {
+		t.Fatalf("SQLite.forUpdate = %q, want unchanged", got)
+	}
+
+	const insert = "INSERT INTO t (a) VALUES (?)"
+
+	if got, want := MySQL.insertIfAbsent(insert), "INSERT IGNORE INTO t (a) VALUES (?)"; got != want {
+		t.Fatalf("MySQL.insertIfAbsent = %q, want %q", got, want)
+	}
+	if got, want := Postgres.insertIfAbsent(insert), insert+" ON CONFLICT DO NOTHING"; got != want {
+		t.Fatalf("Postgres.insertIfAbsent = %q, want %q", got, want)
+	}
+}
+
+func TestDialectForKnownDrivers(t *testing.T) {
+	for driver, want := range map[string]Dialect{"pgx": Postgres, "sqlite3": SQLite, "MySQL": MySQL} {
+		got, err := DialectFor(driver)
+		if err != nil || got != want {
+			t.Fatalf("DialectFor(%q) = %+v, %v, want %+v", driver, got, err, want)
+		}
+	}
+
+	if _, err := DialectFor("oracle"); err == nil {
+		t.Fatal("DialectFor(oracle) succeeded, want error")
+	}
+}
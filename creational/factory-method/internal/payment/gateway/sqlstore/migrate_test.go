@@ -0,0 +1,102 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"factory-method/internal/payment/gateway/sqlstore"
+	"factory-method/internal/payment/gateway/sqlstore/sqltest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sqltest.Open()
+	if err != nil {
+		t.Fatalf("sqltest.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func migratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db := openTestDB(t)
+	if err := sqlstore.Migrate(context.Background(), db, sqltest.Dialect); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	return db
+}
+
+func appliedMigrations(t *testing.T, db *sql.DB) []int64 {
+	t.Helper()
+
+	rows, err := db.Query("SELECT version FROM schema_migrations ORDER BY version")
+	if err != nil {
+		t.Fatalf("select schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []int64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			t.Fatalf("scan schema_migrations: %v", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+
+	return versions
+}
+
+func TestMigrationsAreOrderedAndNonEmpty(t *testing.T) {
+	migrations, err := sqlstore.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			t.Fatalf("migration %s has version %d, want %d", migration.Name, migration.Version, i+1)
+		}
+		if len(migration.Statements) == 0 {
+			t.Fatalf("migration %s has no statements", migration.Name)
+		}
+	}
+}
+
+func TestMigrateAppliesEachMigrationOnce(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := sqlstore.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations: %v", err)
+	}
+
+	for range 2 {
+		if err := sqlstore.Migrate(ctx, db, sqltest.Dialect); err != nil {
+			t.Fatalf("Migrate: %v", err)
+		}
+
+		applied := appliedMigrations(t, db)
+		if len(applied) != len(migrations) {
+			t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+		}
+		for i, version := range applied {
+			if version != migrations[i].Version {
+				t.Fatalf("applied[%d] = %d, want %d", i, version, migrations[i].Version)
+			}
+		}
+	}
+}
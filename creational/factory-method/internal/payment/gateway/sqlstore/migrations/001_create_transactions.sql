@@ -0,0 +1,25 @@
+CREATE TABLE transactions (
+    transaction_id VARCHAR(64) NOT NULL PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL,
+    captured_amount BIGINT NOT NULL,
+    refunded_amount BIGINT NOT NULL,
+    authorization_expires_at BIGINT NOT NULL,
+    error_message TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE refunds (
+    refund_id VARCHAR(64) NOT NULL PRIMARY KEY,
+    transaction_id VARCHAR(64) NOT NULL,
+    sequence BIGINT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX refunds_transaction_id_idx ON refunds (transaction_id, sequence);
@@ -0,0 +1,15 @@
+CREATE TABLE transaction_events (
+    event_id VARCHAR(64) NOT NULL PRIMARY KEY,
+    transaction_id VARCHAR(64) NOT NULL,
+    sequence BIGINT NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    from_status VARCHAR(32) NOT NULL,
+    to_status VARCHAR(32) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX transaction_events_transaction_id_idx ON transaction_events (transaction_id, sequence);
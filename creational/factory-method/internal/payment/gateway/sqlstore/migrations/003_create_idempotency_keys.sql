@@ -0,0 +1,10 @@
+CREATE TABLE idempotency_keys (
+    provider VARCHAR(32) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    result TEXT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    PRIMARY KEY (provider, idempotency_key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (provider, expires_at);
@@ -0,0 +1 @@
+ALTER TABLE transactions ADD COLUMN fee BIGINT NOT NULL DEFAULT 0;
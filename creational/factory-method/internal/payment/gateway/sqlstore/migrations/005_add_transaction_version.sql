@@ -0,0 +1 @@
+ALTER TABLE transactions ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
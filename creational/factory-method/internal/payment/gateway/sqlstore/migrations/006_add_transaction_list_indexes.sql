@@ -0,0 +1,4 @@
+CREATE INDEX transactions_provider_created_at_idx ON transactions (provider, created_at, transaction_id);
+CREATE INDEX transactions_provider_amount_idx ON transactions (provider, amount, transaction_id);
+CREATE INDEX transactions_provider_status_idx ON transactions (provider, status, created_at);
+CREATE INDEX transactions_provider_currency_idx ON transactions (provider, currency, created_at);
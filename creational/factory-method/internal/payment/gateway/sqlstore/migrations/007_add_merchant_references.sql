@@ -0,0 +1,6 @@
+ALTER TABLE transactions ADD COLUMN merchant_reference VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN metadata TEXT;
+ALTER TABLE refunds ADD COLUMN merchant_reference VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE refunds ADD COLUMN metadata TEXT;
+
+CREATE INDEX transactions_provider_merchant_reference_idx ON transactions (provider, merchant_reference, created_at);
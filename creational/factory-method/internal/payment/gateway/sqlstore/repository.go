@@ -0,0 +1,578 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrStaleVersion        = errors.New("transaction version is stale")
+)
+
+const transactionColumns = "transaction_id, status, currency, amount, captured_amount, refunded_amount, fee, " +
+	"authorization_expires_at, error_message, created_at, updated_at, version, merchant_reference, metadata"
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+type Repository struct {
+	db       *sql.DB
+	dialect  Dialect
+	provider string
+}
+
+func NewRepository(db *sql.DB, dialect Dialect, provider string) *Repository {
+	return &Repository{
+		db:       db,
+		dialect:  dialect,
+		provider: provider,
+	}
+}
+
+type Tx struct {
+	tx       *sql.Tx
+	dialect  Dialect
+	provider string
+}
+
+func (r *Repository) InTx(ctx context.Context, fn func(*Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlstore: begin: %w", err)
+	}
+
+	if err := fn(&Tx{tx: tx, dialect: r.dialect, provider: r.provider}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlstore: commit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) GetTransaction(ctx context.Context, id string) (*gateway.TransactionStatus, error) {
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = ? AND provider = ?"
+	return getTransaction(ctx, r.db, r.dialect, query, id, r.provider)
+}
+
+func (r *Repository) ListTransactions(ctx context.Context, q gateway.ListQuery) ([]*gateway.TransactionStatus, error) {
+	f := q.Filter
+	if f.Provider != "" && f.Provider != r.provider {
+		return nil, nil
+	}
+
+	column := "created_at"
+	if q.SortBy == gateway.SortByAmount {
+		column = "amount"
+	}
+
+	direction := "ASC"
+	seek := " > (?, ?)"
+	if q.Descending() {
+		direction = "DESC"
+		seek = " < (?, ?)"
+	}
+
+	var (
+		conditions = []string{"provider = ?"}
+		args       = []any{r.provider}
+	)
+
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if len(f.Statuses) > 0 {
+		statuses := make([]any, 0, len(f.Statuses))
+		for _, status := range f.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		where("status IN "+placeholders(len(statuses)), statuses...)
+	}
+	if f.Currency != "" {
+		where("currency = ?", f.Currency)
+	}
+	if f.MerchantReference != "" {
+		where("merchant_reference = ?", f.MerchantReference)
+	}
+	if f.MinAmount != nil {
+		where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		where("amount <= ?", *f.MaxAmount)
+	}
+	if !f.CreatedAfter.IsZero() {
+		where("created_at >= ?", toUnixNano(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		where("created_at < ?", toUnixNano(f.CreatedBefore))
+	}
+	if key, id, ok := q.Position(); ok {
+		where("("+column+", transaction_id)"+seek, key, id)
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + column + " " + direction + ", transaction_id " + direction + " LIMIT ?"
+	args = append(args, int64(q.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: select transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		transactions []*gateway.TransactionStatus
+		ids          []string
+	)
+	for rows.Next() {
+		t, err := scanTransaction(rows, r.provider)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, t)
+		ids = append(ids, t.TransactionID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlstore: select transactions: %w", err)
+	}
+	rows.Close()
+
+	refunds, err := getRefunds(ctx, r.db, r.dialect, ids...)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transactions {
+		t.Refunds = refunds[t.TransactionID]
+	}
+
+	return transactions, nil
+}
+
+func (r *Repository) GetHistory(ctx context.Context, id string) ([]gateway.TransactionEvent, error) {
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(
+		"SELECT event_id, transaction_id, type, from_status, to_status, amount, currency, actor, reason, created_at "+
+			"FROM transaction_events WHERE transaction_id = ? ORDER BY sequence"), id)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: select events: %w", err)
+	}
+	defer rows.Close()
+
+	history := []gateway.TransactionEvent{}
+	for rows.Next() {
+		var (
+			event     gateway.TransactionEvent
+			amount    int64
+			currency  string
+			createdAt int64
+		)
+
+		err := rows.Scan(
+			&event.EventID, &event.TransactionID, &event.Type, &event.FromStatus, &event.ToStatus,
+			&amount, &currency, &event.Actor, &event.Reason, &createdAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("sqlstore: scan event: %w", err)
+		}
+
+		event.Amount = money.New(amount, currency)
+		event.CreatedAt = fromUnixNano(createdAt)
+		history = append(history, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlstore: select events: %w", err)
+	}
+
+	return history, nil
+}
+
+func (r *Repository) LookupIdempotencyKey(ctx context.Context, key, fingerprint string, now time.Time) (*gateway.TransactionStatus, error) {
+	return lookupIdempotencyKey(ctx, r.db, r.dialect, r.provider, key, fingerprint, now)
+}
+
+func (r *Repository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.dialect.rebind("DELETE FROM idempotency_keys WHERE provider = ? AND expires_at <= ?"), r.provider, toUnixNano(now))
+	if err != nil {
+		return 0, fmt.Errorf("sqlstore: delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (t *Tx) LockTransaction(ctx context.Context, id string) (*gateway.TransactionStatus, error) {
+	query := t.dialect.forUpdate("SELECT " + transactionColumns + " FROM transactions WHERE transaction_id = ? AND provider = ?")
+	return getTransaction(ctx, t.tx, t.dialect, query, id, t.provider)
+}
+
+func (t *Tx) InsertTransaction(ctx context.Context, status *gateway.TransactionStatus) error {
+	metadata, err := encodeMetadata(status.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.tx.ExecContext(ctx, t.dialect.rebind(
+		"INSERT INTO transactions (provider, "+transactionColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		t.provider,
+		status.TransactionID,
+		string(status.Status),
+		status.Amount.Currency(),
+		status.Amount.Amount(),
+		status.CapturedAmount.Amount(),
+		status.RefundedAmount.Amount(),
+		status.Fee.Amount(),
+		toUnixNano(status.AuthorizationExpiresAt),
+		status.ErrorMessage,
+		toUnixNano(status.CreatedAt),
+		toUnixNano(status.UpdatedAt),
+		status.Version,
+		status.MerchantReference,
+		metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlstore: insert transaction: %w", err)
+	}
+
+	return t.insertRefunds(ctx, status, 0)
+}
+
+func (t *Tx) UpdateTransaction(ctx context.Context, previous, status *gateway.TransactionStatus) error {
+	result, err := t.tx.ExecContext(ctx, t.dialect.rebind(
+		"UPDATE transactions SET status = ?, captured_amount = ?, refunded_amount = ?, "+
+			"authorization_expires_at = ?, error_message = ?, updated_at = ?, version = ? "+
+			"WHERE transaction_id = ? AND provider = ? AND version = ?"),
+		string(status.Status),
+		status.CapturedAmount.Amount(),
+		status.RefundedAmount.Amount(),
+		toUnixNano(status.AuthorizationExpiresAt),
+		status.ErrorMessage,
+		toUnixNano(status.UpdatedAt),
+		status.Version,
+		status.TransactionID,
+		t.provider,
+		previous.Version,
+	)
+	if err != nil {
+		return fmt.Errorf("sqlstore: update transaction: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlstore: update transaction: %w", err)
+	}
+	if affected == 0 {
+		return ErrStaleVersion
+	}
+
+	return t.insertRefunds(ctx, status, len(previous.Refunds))
+}
+
+func (t *Tx) AppendEvents(ctx context.Context, id string, events ...gateway.TransactionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var sequence int64
+	err := t.tx.QueryRowContext(ctx, t.dialect.rebind(
+		"SELECT COALESCE(MAX(sequence), 0) FROM transaction_events WHERE transaction_id = ?"), id,
+	).Scan(&sequence)
+	if err != nil {
+		return fmt.Errorf("sqlstore: select event sequence: %w", err)
+	}
+
+	for _, event := range events {
+		sequence++
+
+		_, err := t.tx.ExecContext(ctx, t.dialect.rebind(
+			"INSERT INTO transaction_events (event_id, transaction_id, sequence, type, from_status, to_status, "+
+				"amount, currency, actor, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			event.EventID,
+			id,
+			sequence,
+			string(event.Type),
+			string(event.FromStatus),
+			string(event.ToStatus),
+			event.Amount.Amount(),
+			event.Amount.Currency(),
+			event.Actor,
+			event.Reason,
+			toUnixNano(event.CreatedAt),
+		)
+		if err != nil {
+			return fmt.Errorf("sqlstore: insert event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (t *Tx) LookupIdempotencyKey(ctx context.Context, key, fingerprint string, now time.Time) (*gateway.TransactionStatus, error) {
+	return lookupIdempotencyKey(ctx, t.tx, t.dialect, t.provider, key, fingerprint, now)
+}
+
+func (t *Tx) PutIdempotencyKey(ctx context.Context, key, fingerprint string, result *gateway.TransactionStatus, now, expiresAt time.Time) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("sqlstore: encode idempotency result: %w", err)
+	}
+
+	_, err = t.tx.ExecContext(ctx, t.dialect.rebind(
+		"DELETE FROM idempotency_keys WHERE provider = ? AND idempotency_key = ? AND expires_at <= ?"),
+		t.provider, key, toUnixNano(now),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlstore: delete expired idempotency key: %w", err)
+	}
+
+	inserted, err := t.tx.ExecContext(ctx, t.dialect.rebind(t.dialect.insertIfAbsent(
+		"INSERT INTO idempotency_keys (provider, idempotency_key, fingerprint, result, expires_at) VALUES (?, ?, ?, ?, ?)")),
+		t.provider, key, fingerprint, string(encoded), toUnixNano(expiresAt),
+	)
+	if err != nil {
+		return fmt.Errorf("sqlstore: insert idempotency key: %w", err)
+	}
+
+	affected, err := inserted.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sqlstore: insert idempotency key: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	_, err = lookupIdempotencyKey(ctx, t.tx, t.dialect, t.provider, key, fingerprint, now)
+	if err == nil || errors.Is(err, gateway.ErrIdempotencyKeyNotFound) {
+		return gateway.ErrIdempotencyKeyInProgress
+	}
+
+	return err
+}
+
+func (t *Tx) insertRefunds(ctx context.Context, status *gateway.TransactionStatus, from int) error {
+	for i := from; i < len(status.Refunds); i++ {
+		refund := status.Refunds[i]
+
+		metadata, err := encodeMetadata(refund.Metadata)
+		if err != nil {
+			return err
+		}
+
+		_, err = t.tx.ExecContext(ctx, t.dialect.rebind(
+			"INSERT INTO refunds (refund_id, transaction_id, sequence, amount, currency, reason, created_at, merchant_reference, metadata) "+
+				"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			refund.RefundID,
+			status.TransactionID,
+			int64(i),
+			refund.Amount.Amount(),
+			refund.Amount.Currency(),
+			refund.Reason,
+			toUnixNano(refund.CreatedAt),
+			refund.MerchantReference,
+			metadata,
+		)
+		if err != nil {
+			return fmt.Errorf("sqlstore: insert refund: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func getTransaction(ctx context.Context, q querier, dialect Dialect, query string, id, provider string) (*gateway.TransactionStatus, error) {
+	t, err := scanTransaction(q.QueryRowContext(ctx, dialect.rebind(query), id, provider), provider)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refunds, err := getRefunds(ctx, q, dialect, id)
+	if err != nil {
+		return nil, err
+	}
+	t.Refunds = refunds[id]
+
+	return t, nil
+}
+
+func scanTransaction(row scanner, provider string) (*gateway.TransactionStatus, error) {
+	var (
+		t                               gateway.TransactionStatus
+		currency                        string
+		amount, captured, refunded, fee int64
+		expiresAt, createdAt, updatedAt int64
+		metadata                        sql.NullString
+	)
+
+	err := row.Scan(
+		&t.TransactionID, &t.Status, &currency, &amount, &captured, &refunded, &fee,
+		&expiresAt, &t.ErrorMessage, &createdAt, &updatedAt, &t.Version, &t.MerchantReference, &metadata,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: select transaction: %w", err)
+	}
+
+	t.Provider = provider
+	t.Amount = money.New(amount, currency)
+	t.CapturedAmount = money.New(captured, currency)
+	t.RefundedAmount = money.New(refunded, currency)
+	t.Fee = money.New(fee, currency)
+	t.AuthorizationExpiresAt = fromUnixNano(expiresAt)
+	t.CreatedAt = fromUnixNano(createdAt)
+	t.UpdatedAt = fromUnixNano(updatedAt)
+
+	if t.Metadata, err = decodeMetadata(metadata.String); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func getRefunds(ctx context.Context, q querier, dialect Dialect, ids ...string) (map[string][]gateway.RefundRecord, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	rows, err := q.QueryContext(ctx, dialect.rebind(
+		"SELECT transaction_id, refund_id, amount, currency, reason, created_at, merchant_reference, metadata FROM refunds "+
+			"WHERE transaction_id IN "+placeholders(len(ids))+" ORDER BY transaction_id, sequence"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: select refunds: %w", err)
+	}
+	defer rows.Close()
+
+	refunds := make(map[string][]gateway.RefundRecord, len(ids))
+	for rows.Next() {
+		var (
+			refund        gateway.RefundRecord
+			transactionID string
+			amount        int64
+			currency      string
+			createdAt     int64
+			metadata      sql.NullString
+		)
+
+		err := rows.Scan(&transactionID, &refund.RefundID, &amount, &currency, &refund.Reason, &createdAt, &refund.MerchantReference, &metadata)
+		if err != nil {
+			return nil, fmt.Errorf("sqlstore: scan refund: %w", err)
+		}
+
+		if refund.Metadata, err = decodeMetadata(metadata.String); err != nil {
+			return nil, err
+		}
+
+		refund.Amount = money.New(amount, currency)
+		refund.CreatedAt = fromUnixNano(createdAt)
+		refunds[transactionID] = append(refunds[transactionID], refund)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlstore: select refunds: %w", err)
+	}
+
+	return refunds, nil
+}
+
+func lookupIdempotencyKey(ctx context.Context, q querier, dialect Dialect, provider, key, fingerprint string, now time.Time) (*gateway.TransactionStatus, error) {
+	var (
+		storedFingerprint string
+		encoded           string
+		expiresAt         int64
+	)
+
+	err := q.QueryRowContext(ctx, dialect.rebind(
+		"SELECT fingerprint, result, expires_at FROM idempotency_keys WHERE provider = ? AND idempotency_key = ?"), provider, key,
+	).Scan(&storedFingerprint, &encoded, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlstore: select idempotency key: %w", err)
+	}
+
+	if !now.Before(fromUnixNano(expiresAt)) {
+		return nil, gateway.ErrIdempotencyKeyNotFound
+	}
+
+	if storedFingerprint != fingerprint {
+		return nil, gateway.ErrIdempotencyKeyReused
+	}
+
+	var result gateway.TransactionStatus
+	if err := json.Unmarshal([]byte(encoded), &result); err != nil {
+		return nil, fmt.Errorf("sqlstore: decode idempotency result: %w", err)
+	}
+
+	return &result, nil
+}
+
+func encodeMetadata(metadata gateway.Metadata) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("sqlstore: encode metadata: %w", err)
+	}
+
+	return string(encoded), nil
+}
+
+func decodeMetadata(encoded string) (gateway.Metadata, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	var metadata gateway.Metadata
+	if err := json.Unmarshal([]byte(encoded), &metadata); err != nil {
+		return nil, fmt.Errorf("sqlstore: decode metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func toUnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
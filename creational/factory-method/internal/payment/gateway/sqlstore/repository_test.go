@@ -0,0 +1,278 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/sqlstore"
+	"factory-method/internal/payment/gateway/sqlstore/sqltest"
+	"factory-method/pkg/money"
+)
+
+func testTransaction(id string) *gateway.TransactionStatus {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	return &gateway.TransactionStatus{
+		TransactionID:     id,
+		Provider:          "stripe",
+		Status:            gateway.StatusCompleted,
+		Amount:            money.New(1000, "USD"),
+		CapturedAmount:    money.New(1000, "USD"),
+		RefundedAmount:    money.Zero("USD"),
+		Fee:               money.New(30, "USD"),
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Version:           1,
+		MerchantReference: "order-42",
+		Metadata:          gateway.Metadata{"channel": "web"},
+	}
+}
+
+func insert(t *testing.T, repo *sqlstore.Repository, status *gateway.TransactionStatus) {
+	t.Helper()
+
+	err := repo.InTx(context.Background(), func(tx *sqlstore.Tx) error {
+		return tx.InsertTransaction(context.Background(), status)
+	})
+	if err != nil {
+		t.Fatalf("InsertTransaction: %v", err)
+	}
+}
+
+func refunded(status *gateway.TransactionStatus, amount int64) *gateway.TransactionStatus {
+	next := status.Clone()
+	next.Status = gateway.StatusPartiallyRefunded
+	next.RefundedAmount = money.New(status.RefundedAmount.Amount()+amount, "USD")
+	next.Refunds = append(next.Refunds, gateway.RefundRecord{
+		RefundID:  fmt.Sprintf("re-%d", len(next.Refunds)+1),
+		Amount:    money.New(amount, "USD"),
+		CreatedAt: status.UpdatedAt,
+	})
+	next.Version++
+
+	return next
+}
+
+func TestRepositoryRoundTripsTransactions(t *testing.T) {
+	repo := sqlstore.NewRepository(migratedDB(t), sqltest.Dialect, "stripe")
+	ctx := context.Background()
+
+	created := testTransaction("txn-1")
+	insert(t, repo, created)
+
+	got, err := repo.GetTransaction(ctx, "txn-1")
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if got.Amount != created.Amount || got.Fee != created.Fee || got.Version != 1 ||
+		got.MerchantReference != "order-42" || got.Metadata["channel"] != "web" || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("GetTransaction = %+v, want %+v", got, created)
+	}
+
+	updated := refunded(got, 250)
+	err = repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+		return tx.UpdateTransaction(ctx, got, updated)
+	})
+	if err != nil {
+		t.Fatalf("UpdateTransaction: %v", err)
+	}
+
+	got, err = repo.GetTransaction(ctx, "txn-1")
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if got.Status != gateway.StatusPartiallyRefunded || got.RefundedAmount.Amount() != 250 || len(got.Refunds) != 1 || got.Version != 2 {
+		t.Fatalf("after update = %s refunded %s with %d refunds at version %d", got.Status, got.RefundedAmount, len(got.Refunds), got.Version)
+	}
+}
+
+func TestRepositoryRejectsStaleUpdates(t *testing.T) {
+	repo := sqlstore.NewRepository(migratedDB(t), sqltest.Dialect, "stripe")
+	ctx := context.Background()
+
+	original := testTransaction("txn-1")
+	insert(t, repo, original)
+
+	if err := repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+		return tx.UpdateTransaction(ctx, original, refunded(original, 100))
+	}); err != nil {
+		t.Fatalf("UpdateTransaction: %v", err)
+	}
+
+	err := repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+		return tx.UpdateTransaction(ctx, original, refunded(original, 200))
+	})
+	if !errors.Is(err, sqlstore.ErrStaleVersion) {
+		t.Fatalf("stale UpdateTransaction = %v, want ErrStaleVersion", err)
+	}
+
+	got, err := repo.GetTransaction(ctx, "txn-1")
+	if err != nil {
+		t.Fatalf("GetTransaction: %v", err)
+	}
+	if got.RefundedAmount.Amount() != 100 || len(got.Refunds) != 1 {
+		t.Fatalf("refunded %s with %d refunds, want 1.00 USD with 1", got.RefundedAmount, len(got.Refunds))
+	}
+}
+
+func TestRepositoryScopesRowsByProvider(t *testing.T) {
+	db := migratedDB(t)
+	ctx := context.Background()
+
+	stripe := sqlstore.NewRepository(db, sqltest.Dialect, "stripe")
+	paypal := sqlstore.NewRepository(db, sqltest.Dialect, "paypal")
+
+	insert(t, stripe, testTransaction("txn-1"))
+
+	if _, err := paypal.GetTransaction(ctx, "txn-1"); !errors.Is(err, sqlstore.ErrTransactionNotFound) {
+		t.Fatalf("paypal GetTransaction = %v, want ErrTransactionNotFound", err)
+	}
+
+	err := paypal.InTx(ctx, func(tx *sqlstore.Tx) error {
+		_, err := tx.LockTransaction(ctx, "txn-1")
+		return err
+	})
+	if !errors.Is(err, sqlstore.ErrTransactionNotFound) {
+		t.Fatalf("paypal LockTransaction = %v, want ErrTransactionNotFound", err)
+	}
+}
+
+func TestRepositoryIdempotencyKeys(t *testing.T) {
+	repo := sqlstore.NewRepository(migratedDB(t), sqltest.Dialect, "stripe")
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	result := testTransaction("txn-1")
+
+	err := repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+		return tx.PutIdempotencyKey(ctx, "key-1", "fp-1", result, now, now.Add(time.Hour))
+	})
+	if err != nil {
+		t.Fatalf("PutIdempotencyKey: %v", err)
+	}
+
+	got, err := repo.LookupIdempotencyKey(ctx, "key-1", "fp-1", now)
+	if err != nil {
+		t.Fatalf("LookupIdempotencyKey: %v", err)
+	}
+	if got.TransactionID != "txn-1" {
+		t.Fatalf("replayed %s, want txn-1", got.TransactionID)
+	}
+
+	if _, err := repo.LookupIdempotencyKey(ctx, "key-1", "fp-2", now); !errors.Is(err, gateway.ErrIdempotencyKeyReused) {
+		t.Fatalf("lookup with other fingerprint = %v, want ErrIdempotencyKeyReused", err)
+	}
+	if _, err := repo.LookupIdempotencyKey(ctx, "key-1", "fp-1", now.Add(2*time.Hour)); !errors.Is(err, gateway.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("lookup after expiry = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+
+	purged, err := repo.DeleteExpiredIdempotencyKeys(ctx, now.Add(2*time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("DeleteExpiredIdempotencyKeys = %d, %v, want 1", purged, err)
+	}
+}
+
+func TestRepositoryListPushesFiltersAndLimitDown(t *testing.T) {
+	repo := sqlstore.NewRepository(migratedDB(t), sqltest.Dialect, "stripe")
+	ctx := context.Background()
+
+	statuses := []gateway.TransactionStatusType{gateway.StatusCompleted, gateway.StatusFailed, gateway.StatusPartiallyRefunded}
+	for i := range 9 {
+		status := testTransaction(fmt.Sprintf("txn-%d", i))
+		status.Status = statuses[i%len(statuses)]
+		status.Amount = money.New(int64(100*(i%3)), "USD")
+		status.CreatedAt = status.CreatedAt.Add(time.Duration(i/2) * time.Minute)
+		if status.Status == gateway.StatusPartiallyRefunded {
+			status = refunded(status, 10)
+			status.Refunds[0].RefundID = "re-" + status.TransactionID
+		}
+		insert(t, repo, status)
+	}
+
+	q, err := gateway.ListQuery{
+		Filter: gateway.ListFilter{Statuses: []gateway.TransactionStatusType{gateway.StatusCompleted, gateway.StatusPartiallyRefunded}},
+		SortBy: gateway.SortByAmount,
+		Order:  gateway.SortAscending,
+		Limit:  2,
+	}.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+
+	var listed []string
+	for {
+		transactions, err := repo.ListTransactions(ctx, q)
+		if err != nil {
+			t.Fatalf("ListTransactions: %v", err)
+		}
+		if len(transactions) > q.Limit+1 {
+			t.Fatalf("ListTransactions returned %d rows for limit %d", len(transactions), q.Limit)
+		}
+
+		page := q.Page(transactions, false)
+		for _, status := range page.Transactions {
+			if want := status.Status == gateway.StatusPartiallyRefunded; want != (len(status.Refunds) == 1) {
+				t.Fatalf("%s has %d refunds", status.TransactionID, len(status.Refunds))
+			}
+			listed = append(listed, status.TransactionID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+
+		q.Cursor = page.NextCursor
+		if q, err = q.Normalize(); err != nil {
+			t.Fatalf("Normalize(cursor): %v", err)
+		}
+	}
+
+	want := []string{"txn-0", "txn-3", "txn-6", "txn-2", "txn-5", "txn-8"}
+	if !slices.Equal(listed, want) {
+		t.Fatalf("listed %v, want %v", listed, want)
+	}
+
+	other, err := repo.ListTransactions(ctx, gateway.ListQuery{Filter: gateway.ListFilter{Provider: "paypal"}, Limit: 10})
+	if err != nil || len(other) != 0 {
+		t.Fatalf("ListTransactions(paypal) = %d rows, %v, want none", len(other), err)
+	}
+}
+
+func TestRepositoryIdempotencyKeyInsertKeepsFirstWriter(t *testing.T) {
+	repo := sqlstore.NewRepository(migratedDB(t), sqltest.Dialect, "stripe")
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	put := func(fingerprint string, result *gateway.TransactionStatus, at time.Time) error {
+		return repo.InTx(ctx, func(tx *sqlstore.Tx) error {
+			return tx.PutIdempotencyKey(ctx, "key-1", fingerprint, result, at, at.Add(time.Hour))
+		})
+	}
+
+	if err := put("fp-1", testTransaction("txn-1"), now); err != nil {
+		t.Fatalf("PutIdempotencyKey: %v", err)
+	}
+	if err := put("fp-1", testTransaction("txn-2"), now); !errors.Is(err, gateway.ErrIdempotencyKeyInProgress) {
+		t.Fatalf("duplicate PutIdempotencyKey = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if err := put("fp-2", testTransaction("txn-3"), now); !errors.Is(err, gateway.ErrIdempotencyKeyReused) {
+		t.Fatalf("PutIdempotencyKey with other fingerprint = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	got, err := repo.LookupIdempotencyKey(ctx, "key-1", "fp-1", now)
+	if err != nil || got.TransactionID != "txn-1" {
+		t.Fatalf("LookupIdempotencyKey = %v, %v, want txn-1", got, err)
+	}
+
+	later := now.Add(2 * time.Hour)
+	if err := put("fp-2", testTransaction("txn-4"), later); err != nil {
+		t.Fatalf("PutIdempotencyKey after expiry: %v", err)
+	}
+	if got, err := repo.LookupIdempotencyKey(ctx, "key-1", "fp-2", later); err != nil || got.TransactionID != "txn-4" {
+		t.Fatalf("LookupIdempotencyKey after expiry = %v, %v, want txn-4", got, err)
+	}
+}
@@ -0,0 +1,424 @@
+package sqltest
+
+import (
+	"cmp"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+type table struct {
+	columns    []string
+	primaryKey []int
+	rows       [][]driver.Value
+}
+
+type database struct {
+	tables map[string]*table
+	mu     sync.Mutex
+}
+
+func (db *database) snapshot() map[string]*table {
+	tables := make(map[string]*table, len(db.tables))
+	for name, t := range db.tables {
+		rows := make([][]driver.Value, len(t.rows))
+		for i, row := range t.rows {
+			rows[i] = slices.Clone(row)
+		}
+
+		tables[name] = &table{
+			columns:    t.columns,
+			primaryKey: t.primaryKey,
+			rows:       rows,
+		}
+	}
+
+	return tables
+}
+
+func (db *database) exec(s *statement, args []driver.Value) (int64, error) {
+	switch s.kind {
+	case kindCreateTable:
+		return 0, db.createTable(s)
+	case kindCreateIndex:
+		return 0, nil
+	case kindAlterTable:
+		return 0, db.addColumn(s, args)
+	case kindInsert:
+		return db.insert(s, args)
+	case kindUpdate:
+		return db.update(s, args)
+	case kindDelete:
+		return db.delete(s, args)
+	default:
+		return 0, fmt.Errorf("sqltest: statement does not support Exec")
+	}
+}
+
+func (db *database) query(s *statement, args []driver.Value) (*rows, error) {
+	if s.kind != kindSelect {
+		return nil, fmt.Errorf("sqltest: statement does not support Query")
+	}
+
+	t, err := db.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+
+	matched, err := t.match(s.where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.orderBy) > 0 {
+		columns := make([]int, len(s.orderBy))
+		for i, order := range s.orderBy {
+			if columns[i], err = t.column(order.column); err != nil {
+				return nil, err
+			}
+		}
+
+		sort.SliceStable(matched, func(i, j int) bool {
+			for k, order := range s.orderBy {
+				result, _ := compare(matched[i][columns[k]], matched[j][columns[k]])
+				if result == 0 {
+					continue
+				}
+				if order.descending {
+					return result > 0
+				}
+				return result < 0
+			}
+			return false
+		})
+	}
+
+	if s.limit != nil {
+		limit, ok := s.limit.eval(args).(int64)
+		if !ok || limit < 0 {
+			return nil, fmt.Errorf("sqltest: LIMIT must be a non-negative integer")
+		}
+		matched = matched[:min(int64(len(matched)), limit)]
+	}
+
+	result := &rows{columns: make([]string, len(s.items))}
+	indexes := make([]int, len(s.items))
+	aggregate := false
+
+	for i, item := range s.items {
+		column, err := t.column(item.column)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes[i] = column
+		result.columns[i] = item.column
+		aggregate = aggregate || item.maximum
+	}
+
+	if aggregate {
+		row := make([]driver.Value, len(s.items))
+		for i, item := range s.items {
+			row[i] = item.fallback.eval(args)
+			for j, candidate := range matched {
+				value := candidate[indexes[i]]
+				if order, _ := compare(value, row[i]); j == 0 || order > 0 {
+					row[i] = value
+				}
+			}
+		}
+		result.values = [][]driver.Value{row}
+
+		return result, nil
+	}
+
+	for _, candidate := range matched {
+		row := make([]driver.Value, len(indexes))
+		for i, column := range indexes {
+			row[i] = candidate[column]
+		}
+		result.values = append(result.values, row)
+	}
+
+	return result, nil
+}
+
+func (db *database) createTable(s *statement) error {
+	if _, ok := db.tables[s.table]; ok {
+		if s.ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("sqltest: table %q already exists", s.table)
+	}
+
+	t := &table{columns: s.columns}
+	for _, name := range s.primaryKey {
+		column, err := t.column(name)
+		if err != nil {
+			return err
+		}
+		t.primaryKey = append(t.primaryKey, column)
+	}
+
+	db.tables[s.table] = t
+
+	return nil
+}
+
+func (db *database) addColumn(s *statement, args []driver.Value) error {
+	t, err := db.table(s.table)
+	if err != nil {
+		return err
+	}
+
+	if _, err := t.column(s.columns[0]); err == nil {
+		return fmt.Errorf("sqltest: column %q already exists in table %q", s.columns[0], s.table)
+	}
+
+	t.columns = append(slices.Clone(t.columns), s.columns[0])
+
+	value := normalize(s.values[0].eval(args))
+	for i := range t.rows {
+		t.rows[i] = append(t.rows[i], value)
+	}
+
+	return nil
+}
+
+func (db *database) insert(s *statement, args []driver.Value) (int64, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return 0, err
+	}
+
+	row := make([]driver.Value, len(t.columns))
+	for i, name := range s.columns {
+		column, err := t.column(name)
+		if err != nil {
+			return 0, err
+		}
+		row[column] = normalize(s.values[i].eval(args))
+	}
+
+	for _, existing := range t.rows {
+		if t.samePrimaryKey(existing, row) {
+			if s.skipConflict {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("sqltest: duplicate primary key in table %q", s.table)
+		}
+	}
+
+	t.rows = append(t.rows, row)
+
+	return 1, nil
+}
+
+func (db *database) update(s *statement, args []driver.Value) (int64, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return 0, err
+	}
+
+	columns := make([]int, len(s.columns))
+	for i, name := range s.columns {
+		if columns[i], err = t.column(name); err != nil {
+			return 0, err
+		}
+	}
+
+	var affected int64
+	for _, row := range t.rows {
+		ok, err := t.matches(row, s.where, args)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+
+		for i, column := range columns {
+			row[column] = normalize(s.values[i].eval(args))
+		}
+		affected++
+	}
+
+	return affected, nil
+}
+
+func (db *database) delete(s *statement, args []driver.Value) (int64, error) {
+	t, err := db.table(s.table)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := t.rows[:0]
+	var affected int64
+	for _, row := range t.rows {
+		ok, err := t.matches(row, s.where, args)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			affected++
+			continue
+		}
+		kept = append(kept, row)
+	}
+
+	clear(t.rows[len(kept):])
+	t.rows = kept
+
+	return affected, nil
+}
+
+func (db *database) table(name string) (*table, error) {
+	t, ok := db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("sqltest: no such table %q", name)
+	}
+	return t, nil
+}
+
+func (t *table) column(name string) (int, error) {
+	for i, column := range t.columns {
+		if column == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("sqltest: no such column %q", name)
+}
+
+func (t *table) samePrimaryKey(a, b []driver.Value) bool {
+	if len(t.primaryKey) == 0 {
+		return false
+	}
+
+	for _, column := range t.primaryKey {
+		if order, ok := compare(a[column], b[column]); !ok || order != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *table) match(conditions []condition, args []driver.Value) ([][]driver.Value, error) {
+	var matched [][]driver.Value
+	for _, row := range t.rows {
+		ok, err := t.matches(row, conditions, args)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, row)
+		}
+	}
+
+	return matched, nil
+}
+
+func (t *table) matches(row []driver.Value, conditions []condition, args []driver.Value) (bool, error) {
+	for _, c := range conditions {
+		columns := make([]int, len(c.columns))
+		for i, name := range c.columns {
+			column, err := t.column(name)
+			if err != nil {
+				return false, err
+			}
+			columns[i] = column
+		}
+
+		if c.op == "in" {
+			if !slices.ContainsFunc(c.values, func(value expression) bool {
+				order, ok := compare(row[columns[0]], normalize(value.eval(args)))
+				return ok && order == 0
+			}) {
+				return false, nil
+			}
+			continue
+		}
+
+		var order int
+		for i, column := range columns {
+			result, ok := compare(row[column], normalize(c.values[i].eval(args)))
+			if !ok {
+				return false, nil
+			}
+			if order = result; order != 0 {
+				break
+			}
+		}
+
+		var match bool
+		switch c.op {
+		case "=":
+			match = order == 0
+		case "!=", "<>":
+			match = order != 0
+		case "<":
+			match = order < 0
+		case "<=":
+			match = order <= 0
+		case ">":
+			match = order > 0
+		case ">=":
+			match = order >= 0
+		}
+
+		if !match {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func normalize(v driver.Value) driver.Value {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UnixNano()
+	default:
+		return v
+	}
+}
+
+func compare(a, b driver.Value) (int, bool) {
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return cmp.Compare(a, b), true
+		case float64:
+			return cmp.Compare(float64(a), b), true
+		}
+	case float64:
+		switch b := b.(type) {
+		case int64:
+			return cmp.Compare(a, float64(b)), true
+		case float64:
+			return cmp.Compare(a, b), true
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), true
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			if a == b {
+				return 0, true
+			}
+			if !a {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,183 @@
+package sqltest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"factory-method/internal/payment/gateway/sqlstore"
+)
+
+const DriverName = "sqltest"
+
+var Dialect = sqlstore.Dialect{Name: DriverName, SupportsForUpdate: true}
+
+var (
+	databases   = make(map[string]*database)
+	databasesMu sync.Mutex
+	opened      atomic.Int64
+)
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+func Open() (*sql.DB, error) {
+	return sql.Open(DriverName, fmt.Sprintf("db-%d", opened.Add(1)))
+}
+
+type Driver struct{}
+
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	databasesMu.Lock()
+	defer databasesMu.Unlock()
+
+	db, ok := databases[dsn]
+	if !ok {
+		db = &database{tables: make(map[string]*table)}
+		databases[dsn] = db
+	}
+
+	return &conn{db: db}, nil
+}
+
+type conn struct {
+	db *database
+	tx *tx
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	statement, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{conn: c, statement: statement}, nil
+}
+
+func (c *conn) Close() error {
+	if c.tx != nil {
+		return c.tx.Rollback()
+	}
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("sqltest: transaction already in progress")
+	}
+
+	c.db.mu.Lock()
+	c.tx = &tx{conn: c, snapshot: c.db.snapshot()}
+
+	return c.tx, nil
+}
+
+func (c *conn) withDatabase(fn func(*database) error) error {
+	if c.tx == nil {
+		c.db.mu.Lock()
+		defer c.db.mu.Unlock()
+	}
+
+	return fn(c.db)
+}
+
+type tx struct {
+	conn     *conn
+	snapshot map[string]*table
+}
+
+func (t *tx) Commit() error {
+	return t.finish(false)
+}
+
+func (t *tx) Rollback() error {
+	return t.finish(true)
+}
+
+func (t *tx) finish(rollback bool) error {
+	if t.conn.tx != t {
+		return driver.ErrBadConn
+	}
+
+	if rollback {
+		t.conn.db.tables = t.snapshot
+	}
+
+	t.conn.tx = nil
+	t.conn.db.mu.Unlock()
+
+	return nil
+}
+
+type stmt struct {
+	conn      *conn
+	statement *statement
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.statement.params
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	var affected int64
+
+	err := s.conn.withDatabase(func(db *database) error {
+		var err error
+		affected, err = db.exec(s.statement, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	var result *rows
+
+	err := s.conn.withDatabase(func(db *database) error {
+		var err error
+		result, err = db.query(s.statement, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+
+	copy(dest, r.values[r.pos])
+	r.pos++
+
+	return nil
+}
@@ -0,0 +1,734 @@
+package sqltest
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type statementKind int
+
+const (
+	kindCreateTable statementKind = iota
+	kindCreateIndex
+	kindAlterTable
+	kindInsert
+	kindSelect
+	kindUpdate
+	kindDelete
+)
+
+type expression struct {
+	param int
+	value driver.Value
+}
+
+func (e expression) eval(args []driver.Value) driver.Value {
+	if e.param >= 0 {
+		return args[e.param]
+	}
+	return e.value
+}
+
+type condition struct {
+	columns []string
+	op      string
+	values  []expression
+}
+
+type ordering struct {
+	column     string
+	descending bool
+}
+
+type selectItem struct {
+	column   string
+	maximum  bool
+	fallback expression
+}
+
+type statement struct {
+	kind         statementKind
+	table        string
+	ifNotExists  bool
+	columns      []string
+	primaryKey   []string
+	values       []expression
+	items        []selectItem
+	where        []condition
+	orderBy      []ordering
+	limit        *expression
+	skipConflict bool
+	params       int
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	params int
+}
+
+func parse(query string) (*statement, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	var s *statement
+	switch {
+	case p.acceptWord("create"):
+		s, err = p.parseCreate()
+	case p.acceptWord("alter"):
+		s, err = p.parseAlter()
+	case p.acceptWord("insert"):
+		s, err = p.parseInsert()
+	case p.acceptWord("select"):
+		s, err = p.parseSelect()
+	case p.acceptWord("update"):
+		s, err = p.parseUpdate()
+	case p.acceptWord("delete"):
+		s, err = p.parseDelete()
+	default:
+		return nil, fmt.Errorf("sqltest: unsupported statement %q", query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqltest: %w in %q", err, query)
+	}
+
+	p.acceptSymbol(";")
+	if !p.done() {
+		return nil, fmt.Errorf("sqltest: unexpected %q in %q", p.peek().text, query)
+	}
+
+	s.params = p.params
+
+	return s, nil
+}
+
+func (p *parser) parseCreate() (*statement, error) {
+	if p.acceptWord("index") || p.acceptWord("unique") {
+		p.pos = len(p.tokens)
+		return &statement{kind: kindCreateIndex}, nil
+	}
+
+	if err := p.expectWord("table"); err != nil {
+		return nil, err
+	}
+
+	s := &statement{kind: kindCreateTable}
+	if p.acceptWord("if") {
+		if err := p.expectWords("not", "exists"); err != nil {
+			return nil, err
+		}
+		s.ifNotExists = true
+	}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	for {
+		definition, err := p.definition()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(definition) > 0 {
+			switch definition[0].text {
+			case "primary":
+				if len(definition) < 5 || definition[1].text != "key" || definition[2].text != "(" {
+					return nil, fmt.Errorf("malformed primary key")
+				}
+				for _, t := range definition[3:] {
+					if t.kind == tokenWord {
+						s.primaryKey = append(s.primaryKey, t.text)
+					}
+				}
+			case "unique", "constraint", "index", "key":
+			default:
+				s.columns = append(s.columns, definition[0].text)
+				for i := 1; i+1 < len(definition); i++ {
+					if definition[i].text == "primary" && definition[i+1].text == "key" {
+						s.primaryKey = []string{definition[0].text}
+					}
+				}
+			}
+		}
+
+		if p.acceptSymbol(")") {
+			return s, nil
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) definition() ([]token, error) {
+	var definition []token
+	depth := 0
+
+	for !p.done() {
+		t := p.peek()
+		if t.kind == tokenSymbol {
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				if depth == 0 {
+					return definition, nil
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					return definition, nil
+				}
+			}
+		}
+
+		definition = append(definition, t)
+		p.pos++
+	}
+
+	return definition, nil
+}
+
+func (p *parser) parseAlter() (*statement, error) {
+	if err := p.expectWord("table"); err != nil {
+		return nil, err
+	}
+
+	s := &statement{kind: kindAlterTable}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	if err := p.expectWord("add"); err != nil {
+		return nil, err
+	}
+	p.acceptWord("column")
+
+	definition, err := p.definition()
+	if err != nil {
+		return nil, err
+	}
+	if len(definition) == 0 || definition[0].kind != tokenWord {
+		return nil, fmt.Errorf("malformed column definition")
+	}
+
+	s.columns = []string{definition[0].text}
+	s.values = []expression{{param: -1}}
+
+	for i := 1; i+1 < len(definition); i++ {
+		if definition[i].text != "default" {
+			continue
+		}
+
+		value := &parser{tokens: definition[i+1 : i+2]}
+		if s.values[0], err = value.expression(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (p *parser) parseInsert() (*statement, error) {
+	if err := p.expectWord("into"); err != nil {
+		return nil, err
+	}
+
+	s := &statement{kind: kindInsert}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	columns, err := p.identifierList()
+	if err != nil {
+		return nil, err
+	}
+	s.columns = columns
+
+	if err := p.expectWord("values"); err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	for {
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		s.values = append(s.values, value)
+
+		if p.acceptSymbol(")") {
+			break
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.values) != len(s.columns) {
+		return nil, fmt.Errorf("%d columns but %d values", len(s.columns), len(s.values))
+	}
+
+	if p.acceptWord("on") {
+		if err := p.expectWords("conflict", "do", "nothing"); err != nil {
+			return nil, err
+		}
+		s.skipConflict = true
+	}
+
+	return s, nil
+}
+
+func (p *parser) parseSelect() (*statement, error) {
+	s := &statement{kind: kindSelect}
+
+	for {
+		item, err := p.selectItem()
+		if err != nil {
+			return nil, err
+		}
+		s.items = append(s.items, item)
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if err := p.expectWord("from"); err != nil {
+		return nil, err
+	}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	if s.where, err = p.where(); err != nil {
+		return nil, err
+	}
+
+	if p.acceptWord("order") {
+		if err := p.expectWord("by"); err != nil {
+			return nil, err
+		}
+		for {
+			column, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+
+			order := ordering{column: column}
+			if p.acceptWord("desc") {
+				order.descending = true
+			} else {
+				p.acceptWord("asc")
+			}
+			s.orderBy = append(s.orderBy, order)
+
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.acceptWord("limit") {
+		limit, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		s.limit = &limit
+	}
+
+	if p.acceptWord("for") {
+		if err := p.expectWord("update"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (p *parser) selectItem() (selectItem, error) {
+	if !p.acceptWord("coalesce") {
+		column, err := p.identifier()
+		return selectItem{column: column}, err
+	}
+
+	if err := p.expectSymbol("("); err != nil {
+		return selectItem{}, err
+	}
+	if err := p.expectWord("max"); err != nil {
+		return selectItem{}, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return selectItem{}, err
+	}
+
+	column, err := p.identifier()
+	if err != nil {
+		return selectItem{}, err
+	}
+
+	if err := p.expectSymbol(")"); err != nil {
+		return selectItem{}, err
+	}
+	if err := p.expectSymbol(","); err != nil {
+		return selectItem{}, err
+	}
+
+	fallback, err := p.expression()
+	if err != nil {
+		return selectItem{}, err
+	}
+
+	if err := p.expectSymbol(")"); err != nil {
+		return selectItem{}, err
+	}
+
+	return selectItem{column: column, maximum: true, fallback: fallback}, nil
+}
+
+func (p *parser) parseUpdate() (*statement, error) {
+	s := &statement{kind: kindUpdate}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	if err := p.expectWord("set"); err != nil {
+		return nil, err
+	}
+
+	for {
+		column, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		s.columns = append(s.columns, column)
+		s.values = append(s.values, value)
+
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if s.where, err = p.where(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (p *parser) parseDelete() (*statement, error) {
+	if err := p.expectWord("from"); err != nil {
+		return nil, err
+	}
+
+	s := &statement{kind: kindDelete}
+
+	table, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+	s.table = table
+
+	if s.where, err = p.where(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (p *parser) where() ([]condition, error) {
+	if !p.acceptWord("where") {
+		return nil, nil
+	}
+
+	var conditions []condition
+	for {
+		var (
+			c   condition
+			err error
+		)
+
+		if p.peek().text == "(" {
+			c.columns, err = p.identifierList()
+		} else {
+			var column string
+			column, err = p.identifier()
+			c.columns = []string{column}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		op := p.peek()
+		switch op.text {
+		case "=", "!=", "<>", "<", "<=", ">", ">=", "in":
+			p.pos++
+		default:
+			return nil, fmt.Errorf("unsupported operator %q", op.text)
+		}
+		c.op = op.text
+
+		if op.text == "in" || len(c.columns) > 1 {
+			c.values, err = p.expressionList()
+		} else {
+			var value expression
+			value, err = p.expression()
+			c.values = []expression{value}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if c.op != "in" && len(c.values) != len(c.columns) {
+			return nil, fmt.Errorf("%d columns compared with %d values", len(c.columns), len(c.values))
+		}
+		if c.op == "in" && len(c.columns) != 1 {
+			return nil, fmt.Errorf("IN supports a single column")
+		}
+
+		conditions = append(conditions, c)
+
+		if !p.acceptWord("and") {
+			return conditions, nil
+		}
+	}
+}
+
+func (p *parser) expression() (expression, error) {
+	t := p.peek()
+	p.pos++
+
+	switch {
+	case t.kind == tokenSymbol && t.text == "?":
+		p.params++
+		return expression{param: p.params - 1}, nil
+	case t.kind == tokenNumber:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return expression{}, err
+		}
+		return expression{param: -1, value: n}, nil
+	case t.kind == tokenString:
+		return expression{param: -1, value: t.text}, nil
+	case t.kind == tokenWord && t.text == "null":
+		return expression{param: -1}, nil
+	default:
+		return expression{}, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+func (p *parser) expressionList() ([]expression, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	var expressions []expression
+	for {
+		expression, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+
+		if p.acceptSymbol(")") {
+			return expressions, nil
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) identifierList() ([]string, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+
+	var identifiers []string
+	for {
+		identifier, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		identifiers = append(identifiers, identifier)
+
+		if p.acceptSymbol(")") {
+			return identifiers, nil
+		}
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) identifier() (string, error) {
+	t := p.peek()
+	if t.kind != tokenWord {
+		return "", fmt.Errorf("expected identifier, got %q", t.text)
+	}
+
+	p.pos++
+
+	return t.text, nil
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenSymbol, text: "<end>"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) acceptWord(word string) bool {
+	if t := p.peek(); t.kind == tokenWord && t.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if t := p.peek(); t.kind == tokenSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expectWord(word string) error {
+	if !p.acceptWord(word) {
+		return fmt.Errorf("expected %q, got %q", word, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) expectWords(words ...string) error {
+	for _, word := range words {
+		if err := p.expectWord(word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return fmt.Errorf("expected %q, got %q", symbol, p.peek().text)
+	}
+	return nil
+}
+
+func tokenize(query string) ([]token, error) {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(string(runes[start:i]))})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i])})
+		case r == '\'':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("sqltest: unterminated string in %q", query)
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						b.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String()})
+		case r == '<' || r == '>' || r == '!':
+			if i+1 < len(runes) && (runes[i+1] == '=' || (r == '<' && runes[i+1] == '>')) {
+				tokens = append(tokens, token{kind: tokenSymbol, text: string(runes[i : i+2])})
+				i += 2
+				continue
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(r)})
+			i++
+		case strings.ContainsRune("(),;*?=", r):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(r)})
+			i++
+		default:
+			return nil, fmt.Errorf("sqltest: unexpected character %q in %q", r, query)
+		}
+	}
+
+	return tokens, nil
+}
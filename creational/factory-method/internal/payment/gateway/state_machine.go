@@ -0,0 +1,60 @@
+package gateway
+
+import "fmt"
+
+type ErrIllegalTransition struct {
+	From TransactionStatusType
+	To   TransactionStatusType
+}
+
+func (e *ErrIllegalTransition) Error() string {
+	return fmt.Sprintf("illegal transaction status transition from %q to %q", e.From, e.To)
+}
+
+func (e *ErrIllegalTransition) Is(target error) bool {
+	return target == ErrConflict
+}
+
+var allowedTransitions = map[TransactionStatusType][]TransactionStatusType{
+	StatusPending: {
+		StatusCompleted,
+		StatusAuthorized,
+		StatusFailed,
+	},
+	StatusAuthorized: {
+		StatusCompleted,
+		StatusVoided,
+		StatusFailed,
+	},
+	StatusCompleted: {
+		StatusPartiallyRefunded,
+		StatusRefund,
+	},
+	StatusPartiallyRefunded: {
+		StatusPartiallyRefunded,
+		StatusRefund,
+	},
+}
+
+func CanTransition(from, to TransactionStatusType) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateTransition(from, to TransactionStatusType) error {
+	if !CanTransition(from, to) {
+		return &ErrIllegalTransition{From: from, To: to}
+	}
+	return nil
+}
+
+func AllowedTransitions(from TransactionStatusType) []TransactionStatusType {
+	allowed := allowedTransitions[from]
+	result := make([]TransactionStatusType, len(allowed))
+	copy(result, allowed)
+	return result
+}
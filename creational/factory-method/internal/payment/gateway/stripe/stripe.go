@@ -2,122 +2,60 @@ package stripe
 
 import (
 	"context"
-	"errors"
+	"database/sql"
+
+	"factory-method/internal/payment/card"
 	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/internal/payment/gateway/sqlstore"
+	"factory-method/internal/payment/gateway/wal"
+	"factory-method/pkg/money"
 )
 
-type Validator interface {
-	Validate(gateway.PaymentDetails) error
-}
-
-type SaveTransaction struct {
-	Amount   float64
-	Currency string
-}
-
-type UpdateTransaction struct {
-	TransactionID string
-	Status        gateway.TransactionStatusType
-}
+const Name = "stripe"
 
-type TransactionStore interface {
-	Save(ctx context.Context, saveTransaction *SaveTransaction) (*gateway.TransactionStatus, error)
-	Get(ctx context.Context, id string) (*gateway.TransactionStatus, error)
-	Update(ctx context.Context, updateTransaction UpdateTransaction) (*gateway.TransactionStatus, error)
-}
-
-type Authenticator interface {
-	Authenticate(card string) bool
-}
-
-type StripePaymentGateway struct {
-	store         TransactionStore
-	validator     Validator
-	authenticator Authenticator
-}
-
-func NewStripePaymentGateway(
-	store TransactionStore,
-	validator Validator,
-	authenticator Authenticator,
-) *StripePaymentGateway {
-	return &StripePaymentGateway{
-		store:         store,
-		validator:     validator,
-		authenticator: authenticator,
+func Provider() engine.Provider {
+	return engine.Provider{
+		Name:            Name,
+		ValidationRules: engine.DefaultValidationRules(),
+		AcceptedBrands: []card.Brand{
+			card.Visa,
+			card.Mastercard,
+			card.Amex,
+			card.Discover,
+			card.JCB,
+			card.DinersClub,
+			card.UnionPay,
+		},
+		Authenticator: engine.NewCardAllowlist(
+			"4242424242424242",
+			"5555555555554444",
+		),
+		Fee: engine.PercentageFee(290,
+			money.New(30, "USD"),
+			money.New(25, "EUR"),
+			money.New(20, "GBP"),
+			money.New(30, "CAD"),
+			money.New(30, "AUD"),
+		),
+		DeclineCodes: map[gateway.DeclineReason]string{
+			gateway.DeclineAuthenticationFailed: "card_declined",
+		},
 	}
 }
 
-func (spg *StripePaymentGateway) ProcessPayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	if err := spg.validator.Validate(details); err != nil {
-		return nil, err
-	}
-
-	if !spg.authenticator.Authenticate(details.CardNumber) {
-		return nil, errors.New("stripe: authentication failed: invalid card")
-	}
-
-	saveTransaction := &SaveTransaction{
-		Amount:   details.Amount,
-		Currency: details.Currency,
-	}
-
-	savedTransaction, err := spg.store.Save(ctx, saveTransaction)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return savedTransaction, nil
+func NewStripePaymentGateway(store engine.TransactionStore, opts ...engine.GatewayOption) *engine.Gateway {
+	return engine.NewGateway(Provider(), store, opts...)
 }
 
-func (spg *StripePaymentGateway) Refund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	transaction, err := spg.store.Get(ctx, details.TransactionID)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if transaction.Status != gateway.StatusCompleted {
-		return nil, errors.New("stripe: cannot refund non-completed transaction")
-	}
-
-	if details.Amount > transaction.Amount {
-		return nil, errors.New("stripe: refund amount exceeds transaction amount")
-	}
-
-	updateTransaction := &UpdateTransaction{
-		TransactionID: details.TransactionID,
-		Status:        gateway.StatusRefund,
-	}
-
-	updatedTransaction, err := spg.store.Update(ctx, *updateTransaction)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return updatedTransaction, nil
+func NewTransactionStore(opts ...engine.StoreOption) *engine.InMemoryTransactionStore {
+	return engine.NewInMemoryTransactionStore(Name, opts...)
 }
 
-func (spg *StripePaymentGateway) GetStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error) {
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
-	}
-
-	transaction, err := spg.store.Get(ctx, transactionID)
-
-	if err != nil {
-		return nil, err
-	}
+func NewFileTransactionStore(dir string, walOptions wal.Options, opts ...engine.StoreOption) (*engine.FileTransactionStore, error) {
+	return engine.NewFileTransactionStore(Name, dir, walOptions, opts...)
+}
 
-	return transaction, nil
+func NewSQLTransactionStore(ctx context.Context, db *sql.DB, dialect sqlstore.Dialect, opts ...engine.StoreOption) (*engine.SQLTransactionStore, error) {
+	return engine.NewSQLTransactionStore(ctx, Name, db, dialect, opts...)
 }
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrValidation = errors.New("validation failed")
+
+const (
+	CodeRequired         = "required"
+	CodeInvalid          = "invalid"
+	CodeInvalidFormat    = "invalid_format"
+	CodeInvalidLength    = "invalid_length"
+	CodeChecksum         = "checksum_failed"
+	CodeUnsupported      = "unsupported"
+	CodeUnsupportedBrand = "unsupported_brand"
+	CodeExpired          = "expired"
+)
+
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for i := range e.Fields {
+		messages = append(messages, e.Fields[i].Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation || target == ErrInvalidRequest
+}
+
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e *ValidationError) Merge(err error) bool {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		e.Fields = append(e.Fields, validationErr.Fields...)
+		return true
+	}
+
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		e.Fields = append(e.Fields, *fieldErr)
+		return true
+	}
+
+	return false
+}
+
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
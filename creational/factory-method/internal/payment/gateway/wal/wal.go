@@ -0,0 +1,625 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+const (
+	logFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+var (
+	ErrClosed  = errors.New("wal: log is closed")
+	ErrCorrupt = errors.New("wal: corrupt record before the end of the log")
+)
+
+type SyncPolicy string
+
+const (
+	SyncAlways   SyncPolicy = "always"
+	SyncInterval SyncPolicy = "interval"
+	SyncNever    SyncPolicy = "never"
+)
+
+type Options struct {
+	SyncPolicy           SyncPolicy
+	SyncInterval         time.Duration
+	CompactEvery         int
+	CompactInterval      time.Duration
+	IdempotencyRetention time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		SyncPolicy:           SyncAlways,
+		SyncInterval:         time.Second,
+		CompactEvery:         1000,
+		IdempotencyRetention: gateway.DefaultIdempotencyRetention,
+	}
+}
+
+type Restorer interface {
+	RestoreTransaction(t *gateway.TransactionStatus)
+	RestoreEvents(transactionID string, events []gateway.TransactionEvent)
+	RestoreIdempotencyKey(key, fingerprint string, result *gateway.TransactionStatus, recordedAt time.Time)
+}
+
+type record struct {
+	Seq    uint64         `json:"seq"`
+	Commit gateway.Commit `json:"commit"`
+}
+
+type idempotencyEntry struct {
+	Key         string                     `json:"key"`
+	Fingerprint string                     `json:"fingerprint"`
+	Result      *gateway.TransactionStatus `json:"result"`
+	RecordedAt  time.Time                  `json:"recorded_at"`
+}
+
+type snapshot struct {
+	Seq          uint64                                `json:"seq"`
+	Transactions []*gateway.TransactionStatus          `json:"transactions"`
+	History      map[string][]gateway.TransactionEvent `json:"history"`
+	Idempotency  []idempotencyEntry                    `json:"idempotency"`
+}
+
+type Log struct {
+	dir    string
+	opts   Options
+	file   *os.File
+	offset int64
+	seq    uint64
+
+	transactions map[string]*gateway.TransactionStatus
+	history      map[string][]gateway.TransactionEvent
+	idempotency  map[string]idempotencyEntry
+
+	sinceCompaction int
+	dirty           bool
+	closed          bool
+	failed          error
+
+	sync      func(*os.File) error
+	compact   chan struct{}
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	compactMu sync.Mutex
+	mu        sync.Mutex
+}
+
+func Open(dir string, opts Options, restorer Restorer) (*Log, error) {
+	if opts.SyncPolicy == "" {
+		opts.SyncPolicy = SyncAlways
+	}
+	if opts.IdempotencyRetention <= 0 {
+		opts.IdempotencyRetention = gateway.DefaultIdempotencyRetention
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("wal: create directory: %w", err)
+	}
+
+	l := &Log{
+		dir:          dir,
+		opts:         opts,
+		transactions: make(map[string]*gateway.TransactionStatus),
+		history:      make(map[string][]gateway.TransactionEvent),
+		idempotency:  make(map[string]idempotencyEntry),
+		sync:         (*os.File).Sync,
+		compact:      make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+	}
+
+	if err := l.loadSnapshot(); err != nil {
+		return nil, err
+	}
+
+	if err := l.replay(); err != nil {
+		return nil, err
+	}
+
+	l.restoreInto(restorer)
+
+	l.startBackground()
+
+	return l, nil
+}
+
+func (l *Log) Append(c gateway.Commit) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if l.failed != nil {
+		return l.failed
+	}
+
+	r := record{Seq: l.seq + 1, Commit: c}
+
+	line, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+
+	n, err := l.file.Write(line)
+	if err != nil {
+		if n > 0 {
+			l.rollbackLocked()
+		}
+		return fmt.Errorf("wal: append record: %w", err)
+	}
+
+	if l.opts.SyncPolicy == SyncAlways {
+		if err := l.sync(l.file); err != nil {
+			l.rollbackLocked()
+			return fmt.Errorf("wal: sync: %w", err)
+		}
+	} else {
+		l.dirty = true
+	}
+
+	l.offset += int64(n)
+	l.seq = r.Seq
+	l.apply(c)
+	l.sinceCompaction++
+
+	if l.opts.CompactEvery > 0 && l.sinceCompaction >= l.opts.CompactEvery {
+		select {
+		case l.compact <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (l *Log) Compact() error {
+	l.compactMu.Lock()
+	defer l.compactMu.Unlock()
+
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrClosed
+	}
+	if l.failed != nil {
+		l.mu.Unlock()
+		return l.failed
+	}
+
+	snap := l.snapshotLocked()
+	offset := l.offset
+	compacted := l.sinceCompaction
+	l.mu.Unlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("wal: encode snapshot: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(l.dir, snapshotFileName), data); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	if err := l.rewriteLocked(offset); err != nil {
+		return err
+	}
+
+	l.sinceCompaction -= compacted
+
+	return nil
+}
+
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	return l.syncLocked()
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.stop)
+	l.mu.Unlock()
+
+	l.wg.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	syncErr := l.file.Sync()
+	closeErr := l.file.Close()
+
+	return errors.Join(syncErr, closeErr)
+}
+
+func (l *Log) startBackground() {
+	var syncTicker, compactTicker *time.Ticker
+
+	if l.opts.SyncPolicy == SyncInterval && l.opts.SyncInterval > 0 {
+		syncTicker = time.NewTicker(l.opts.SyncInterval)
+	}
+
+	if l.opts.CompactInterval > 0 {
+		compactTicker = time.NewTicker(l.opts.CompactInterval)
+	}
+
+	if syncTicker == nil && compactTicker == nil && l.opts.CompactEvery <= 0 {
+		return
+	}
+
+	l.wg.Add(1)
+
+	go func() {
+		defer l.wg.Done()
+		defer stopTicker(syncTicker)
+		defer stopTicker(compactTicker)
+
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-tickerC(syncTicker):
+				l.mu.Lock()
+				_ = l.syncLocked()
+				l.mu.Unlock()
+			case <-l.compact:
+				_ = l.Compact()
+			case <-tickerC(compactTicker):
+				l.mu.Lock()
+				pending := l.sinceCompaction > 0
+				l.mu.Unlock()
+
+				if pending {
+					_ = l.Compact()
+				}
+			}
+		}
+	}()
+}
+
+func (l *Log) syncLocked() error {
+	if !l.dirty {
+		return nil
+	}
+
+	if err := l.sync(l.file); err != nil {
+		return fmt.Errorf("wal: sync: %w", err)
+	}
+
+	l.dirty = false
+
+	return nil
+}
+
+func (l *Log) rollbackLocked() {
+	if err := l.file.Truncate(l.offset); err != nil {
+		l.failed = fmt.Errorf("wal: roll back failed append: %w", err)
+		return
+	}
+
+	if _, err := l.file.Seek(l.offset, io.SeekStart); err != nil {
+		l.failed = fmt.Errorf("wal: roll back failed append: %w", err)
+	}
+}
+
+func (l *Log) rewriteLocked(offset int64) error {
+	tail := make([]byte, l.offset-offset)
+	if _, err := l.file.ReadAt(tail, offset); err != nil {
+		return fmt.Errorf("wal: read log tail: %w", err)
+	}
+
+	path := filepath.Join(l.dir, logFileName)
+
+	if err := writeFileAtomic(path, tail); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		l.failed = fmt.Errorf("wal: reopen log: %w", err)
+		return l.failed
+	}
+
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		_ = file.Close()
+		l.failed = fmt.Errorf("wal: reopen log: %w", err)
+		return l.failed
+	}
+
+	_ = l.file.Close()
+
+	l.file = file
+	l.offset = int64(len(tail))
+	l.dirty = false
+
+	return nil
+}
+
+func (l *Log) snapshotLocked() snapshot {
+	snap := snapshot{
+		Seq:          l.seq,
+		Transactions: make([]*gateway.TransactionStatus, 0, len(l.transactions)),
+		History:      make(map[string][]gateway.TransactionEvent, len(l.history)),
+		Idempotency:  make([]idempotencyEntry, 0, len(l.idempotency)),
+	}
+
+	for _, t := range l.transactions {
+		snap.Transactions = append(snap.Transactions, t)
+	}
+
+	sort.Slice(snap.Transactions, func(i, j int) bool {
+		return snap.Transactions[i].TransactionID < snap.Transactions[j].TransactionID
+	})
+
+	for id, events := range l.history {
+		snap.History[id] = slices.Clone(events)
+	}
+
+	now := time.Now().UTC()
+	for key, entry := range l.idempotency {
+		if !now.Before(entry.RecordedAt.Add(l.opts.IdempotencyRetention)) {
+			delete(l.idempotency, key)
+			continue
+		}
+		snap.Idempotency = append(snap.Idempotency, entry)
+	}
+
+	sort.Slice(snap.Idempotency, func(i, j int) bool {
+		return snap.Idempotency[i].Key < snap.Idempotency[j].Key
+	})
+
+	return snap
+}
+
+func (l *Log) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(l.dir, snapshotFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("wal: read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("wal: decode snapshot: %w", err)
+	}
+
+	l.seq = snap.Seq
+
+	for _, t := range snap.Transactions {
+		l.transactions[t.TransactionID] = t
+	}
+
+	for id, events := range snap.History {
+		l.history[id] = events
+	}
+
+	for _, entry := range snap.Idempotency {
+		l.idempotency[entry.Key] = entry
+	}
+
+	return nil
+}
+
+func (l *Log) replay() error {
+	path := filepath.Join(l.dir, logFileName)
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: open log: %w", err)
+	}
+
+	reader := bufio.NewReader(file)
+
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("wal: read log: %w", err)
+		}
+
+		r, ok := decodeRecord(line)
+		if !ok {
+			if _, err := reader.Peek(1); err == nil {
+				_ = file.Close()
+				return fmt.Errorf("%w at offset %d", ErrCorrupt, offset)
+			}
+			break
+		}
+
+		offset += int64(len(line))
+
+		if r.Seq <= l.seq {
+			continue
+		}
+
+		l.seq = r.Seq
+		l.apply(r.Commit)
+		l.sinceCompaction++
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("wal: truncate torn tail: %w", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("wal: seek log: %w", err)
+	}
+
+	l.file = file
+	l.offset = offset
+
+	return nil
+}
+
+func (l *Log) restoreInto(restorer Restorer) {
+	ids := make([]string, 0, len(l.transactions))
+	for id := range l.transactions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		restorer.RestoreTransaction(l.transactions[id].Clone())
+	}
+
+	for id, events := range l.history {
+		restorer.RestoreEvents(id, append([]gateway.TransactionEvent(nil), events...))
+	}
+
+	for _, entry := range l.idempotency {
+		restorer.RestoreIdempotencyKey(entry.Key, entry.Fingerprint, entry.Result, entry.RecordedAt)
+	}
+}
+
+func (l *Log) apply(c gateway.Commit) {
+	if c.Transaction != nil {
+		l.transactions[c.TransactionID] = c.Transaction.Clone()
+
+		if c.IdempotencyKey != "" {
+			l.idempotency[c.IdempotencyKey] = idempotencyEntry{
+				Key:         c.IdempotencyKey,
+				Fingerprint: c.Fingerprint,
+				Result:      c.Transaction.Clone(),
+				RecordedAt:  c.CommittedAt,
+			}
+		}
+	}
+
+	if len(c.Events) > 0 {
+		l.history[c.TransactionID] = append(l.history[c.TransactionID], c.Events...)
+	}
+}
+
+func encodeRecord(r record) ([]byte, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("wal: encode record: %w", err)
+	}
+
+	checksum := binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(payload))
+
+	line := make([]byte, 0, len(payload)+10)
+	line = append(line, hex.EncodeToString(checksum)...)
+	line = append(line, ' ')
+	line = append(line, payload...)
+	line = append(line, '\n')
+
+	return line, nil
+}
+
+func decodeRecord(line []byte) (record, bool) {
+	if len(line) < 10 || line[len(line)-1] != '\n' || line[8] != ' ' {
+		return record{}, false
+	}
+
+	checksum, err := hex.DecodeString(string(line[:8]))
+	if err != nil {
+		return record{}, false
+	}
+
+	payload := line[9 : len(line)-1]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(checksum) {
+		return record{}, false
+	}
+
+	var r record
+	if err := json.Unmarshal(payload, &r); err != nil {
+		return record{}, false
+	}
+
+	return r, true
+}
+
+func tickerC(t *time.Ticker) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
+
+func stopTicker(t *time.Ticker) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("wal: create %s: %w", filepath.Base(path), err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("wal: write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("wal: sync %s: %w", filepath.Base(path), err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("wal: close %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("wal: install %s: %w", filepath.Base(path), err)
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	defer dir.Close()
+
+	_ = dir.Sync()
+
+	return nil
+}
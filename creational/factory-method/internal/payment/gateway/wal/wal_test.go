@@ -0,0 +1,251 @@
+package wal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+type recorder struct {
+	transactions map[string]*gateway.TransactionStatus
+}
+
+func (r *recorder) RestoreTransaction(t *gateway.TransactionStatus) {
+	r.transactions[t.TransactionID] = t
+}
+
+func (r *recorder) RestoreEvents(string, []gateway.TransactionEvent) {}
+
+func (r *recorder) RestoreIdempotencyKey(string, string, *gateway.TransactionStatus, time.Time) {}
+
+func openTestLog(t *testing.T, dir string, opts Options) (*Log, *recorder) {
+	t.Helper()
+
+	r := &recorder{transactions: make(map[string]*gateway.TransactionStatus)}
+
+	l, err := Open(dir, opts, r)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	return l, r
+}
+
+func testCommit(id string) gateway.Commit {
+	return gateway.Commit{
+		TransactionID: id,
+		Transaction:   &gateway.TransactionStatus{TransactionID: id, Status: gateway.StatusCompleted},
+		CommittedAt:   time.Now().UTC(),
+	}
+}
+
+func TestAppendRollsBackRecordWhenSyncFails(t *testing.T) {
+	dir := t.TempDir()
+
+	l, _ := openTestLog(t, dir, DefaultOptions())
+
+	syncErr := errors.New("disk full")
+	l.sync = func(*os.File) error { return syncErr }
+
+	if err := l.Append(testCommit("txn-lost")); !errors.Is(err, syncErr) {
+		t.Fatalf("Append with failing sync = %v, want %v", err, syncErr)
+	}
+
+	l.sync = (*os.File).Sync
+
+	if err := l.Append(testCommit("txn-kept")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if l.seq != 1 {
+		t.Fatalf("seq = %d, want 1", l.seq)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	_, restored := openTestLog(t, dir, DefaultOptions())
+
+	if _, ok := restored.transactions["txn-lost"]; ok {
+		t.Fatal("replay restored the commit whose sync failed")
+	}
+	if _, ok := restored.transactions["txn-kept"]; !ok {
+		t.Fatal("replay lost the commit appended after the failed sync")
+	}
+}
+
+func TestAppendTriggersCompactionInBackground(t *testing.T) {
+	dir := t.TempDir()
+
+	opts := DefaultOptions()
+	opts.CompactEvery = 5
+
+	l, _ := openTestLog(t, dir, opts)
+
+	for i := range opts.CompactEvery {
+		if err := l.Append(testCommit(fmt.Sprintf("txn-%d", i))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, snapshotFileName)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("compaction did not write a snapshot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCompactionKeepsConcurrentAppends(t *testing.T) {
+	dir := t.TempDir()
+
+	opts := DefaultOptions()
+	opts.SyncPolicy = SyncNever
+	opts.CompactEvery = 7
+
+	l, _ := openTestLog(t, dir, opts)
+
+	const writers, perWriter = 4, 50
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				if err := l.Append(testCommit(fmt.Sprintf("txn-%d-%d", w, i))); err != nil {
+					t.Errorf("Append: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			if err := l.Compact(); err != nil {
+				t.Errorf("Compact: %v", err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	_, restored := openTestLog(t, dir, opts)
+
+	if n := len(restored.transactions); n != writers*perWriter {
+		t.Fatalf("restored %d transactions, want %d", n, writers*perWriter)
+	}
+}
+
+func writeTestLog(t *testing.T, dir string, ids ...string) {
+	t.Helper()
+
+	l, _ := openTestLog(t, dir, DefaultOptions())
+	for _, id := range ids {
+		if err := l.Append(testCommit(id)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestOpenTruncatesTornTail(t *testing.T) {
+	tails := map[string]func(line []byte) []byte{
+		"partial":  func(line []byte) []byte { return line[:len(line)/2] },
+		"checksum": func(line []byte) []byte { return append([]byte("00000000"), line[8:]...) },
+	}
+
+	for name, tail := range tails {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, logFileName)
+
+			writeTestLog(t, dir, "txn-1", "txn-2")
+
+			intact, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+
+			line, err := encodeRecord(record{Seq: 3, Commit: testCommit("txn-3")})
+			if err != nil {
+				t.Fatalf("encodeRecord: %v", err)
+			}
+			if err := os.WriteFile(path, append(intact, tail(line)...), 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			l, restored := openTestLog(t, dir, DefaultOptions())
+
+			if len(restored.transactions) != 2 || restored.transactions["txn-3"] != nil {
+				t.Fatalf("restored %d transactions, want txn-1 and txn-2", len(restored.transactions))
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if info.Size() != int64(len(intact)) {
+				t.Fatalf("log size after reopen = %d, want %d", info.Size(), len(intact))
+			}
+
+			if err := l.Append(testCommit("txn-4")); err != nil {
+				t.Fatalf("Append after truncation: %v", err)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			if _, restored := openTestLog(t, dir, DefaultOptions()); len(restored.transactions) != 3 {
+				t.Fatalf("restored %d transactions after append, want 3", len(restored.transactions))
+			}
+		})
+	}
+}
+
+func TestOpenRejectsCorruptionBeforeTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, logFileName)
+
+	writeTestLog(t, dir, "txn-1", "txn-2", "txn-3")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	second := bytes.IndexByte(data, '\n') + 1
+	data[second+20] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := Open(dir, DefaultOptions(), &recorder{transactions: make(map[string]*gateway.TransactionStatus)}); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open with corrupt middle record = %v, want ErrCorrupt", err)
+	}
+
+	if after, err := os.ReadFile(path); err != nil || !bytes.Equal(after, data) {
+		t.Fatalf("Open rewrote a log with a corrupt middle record: %v", err)
+	}
+}
@@ -11,10 +11,15 @@ type Processor struct {
 	gateway gateway.PaymentGateway
 }
 
-func NewProcessor(factory factory.PaymentGatewayFactory) *Processor {
-	return &Processor{
-		gateway: factory.GetPaymentGateway(),
+func NewProcessor(factory factory.PaymentGatewayFactory) (*Processor, error) {
+	gateway, err := factory.GetPaymentGateway()
+	if err != nil {
+		return nil, fmt.Errorf("factory GetPaymentGateway failed: %w", err)
 	}
+
+	return &Processor{
+		gateway: gateway,
+	}, nil
 }
 
 func (p *Processor) MakePayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
@@ -25,6 +30,30 @@ func (p *Processor) MakePayment(ctx context.Context, details gateway.PaymentDeta
 	return status, nil
 }
 
+func (p *Processor) Authorize(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
+	status, err := p.gateway.Authorize(ctx, details)
+	if err != nil {
+		return nil, fmt.Errorf("gateway Authorize failed: %w", err)
+	}
+	return status, nil
+}
+
+func (p *Processor) Capture(ctx context.Context, details gateway.CaptureDetails) (*gateway.TransactionStatus, error) {
+	status, err := p.gateway.Capture(ctx, details)
+	if err != nil {
+		return nil, fmt.Errorf("gateway Capture failed: %w", err)
+	}
+	return status, nil
+}
+
+func (p *Processor) Void(ctx context.Context, details gateway.VoidDetails) (*gateway.TransactionStatus, error) {
+	status, err := p.gateway.Void(ctx, details)
+	if err != nil {
+		return nil, fmt.Errorf("gateway Void failed: %w", err)
+	}
+	return status, nil
+}
+
 func (p *Processor) MakeRefund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error) {
 	status, err := p.gateway.Refund(ctx, details)
 	if err != nil {
@@ -36,3 +65,27 @@ func (p *Processor) MakeRefund(ctx context.Context, details gateway.RefundDetail
 func (p *Processor) CheckStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error) {
 	return p.gateway.GetStatus(ctx, transactionID)
 }
+
+func (p *Processor) GetHistory(ctx context.Context, transactionID string) ([]gateway.TransactionEvent, error) {
+	history, err := p.gateway.GetHistory(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("gateway GetHistory failed: %w", err)
+	}
+	return history, nil
+}
+
+func (p *Processor) List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error) {
+	page, err := p.gateway.List(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("gateway List failed: %w", err)
+	}
+	return page, nil
+}
+
+func (p *Processor) FindByIdempotencyKey(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, error) {
+	status, err := p.gateway.FindByIdempotencyKey(ctx, key, fingerprint)
+	if err != nil {
+		return nil, fmt.Errorf("gateway FindByIdempotencyKey failed: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,355 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"factory-method/internal/payment/factory"
+	"factory-method/internal/payment/gateway"
+)
+
+const DefaultAssignmentLimit = 100000
+
+var ErrNoRoute = errors.New("no payment provider available for request")
+
+type Route struct {
+	Name      string
+	Metadata  factory.ProviderMetadata
+	Processor *Processor
+}
+
+func NewRoute(provider factory.Provider) (Route, error) {
+	p, err := NewProcessor(provider.Factory)
+	if err != nil {
+		return Route{}, err
+	}
+
+	return Route{
+		Name:      provider.Name,
+		Metadata:  provider.Metadata,
+		Processor: p,
+	}, nil
+}
+
+type RoutingProcessor struct {
+	routes      []Route
+	providers   []Route
+	failover    func(error) bool
+	assignments *assignments
+}
+
+type assignments struct {
+	providers map[assignment]string
+	order     []assignment
+	limit     int
+	mu        sync.RWMutex
+}
+
+type assignment struct {
+	idempotencyKey bool
+	id             string
+}
+
+type RoutingOption func(*RoutingProcessor)
+
+func WithFailoverPolicy(failover func(error) bool) RoutingOption {
+	return func(r *RoutingProcessor) {
+		if failover != nil {
+			r.failover = failover
+		}
+	}
+}
+
+func WithAssignmentLimit(limit int) RoutingOption {
+	return func(r *RoutingProcessor) {
+		if limit > 0 {
+			r.assignments.limit = limit
+		}
+	}
+}
+
+func NewRoutingProcessor(routes []Route, opts ...RoutingOption) *RoutingProcessor {
+	r := &RoutingProcessor{
+		routes:      append([]Route(nil), routes...),
+		providers:   append([]Route(nil), routes...),
+		failover:    IsFailoverable,
+		assignments: &assignments{providers: make(map[assignment]string), limit: DefaultAssignmentLimit},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+func IsFailoverable(err error) bool {
+	return errors.Is(err, gateway.ErrTransient)
+}
+
+func (r *RoutingProcessor) MakePayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
+	return r.route(ctx, details.Amount.Currency(), details.IdempotencyKey, gateway.PaymentFingerprint(details), func(p *Processor) (*gateway.TransactionStatus, error) {
+		return p.MakePayment(ctx, details)
+	})
+}
+
+func (r *RoutingProcessor) Authorize(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error) {
+	return r.route(ctx, details.Amount.Currency(), details.IdempotencyKey, gateway.AuthorizationFingerprint(details), func(p *Processor) (*gateway.TransactionStatus, error) {
+		return p.Authorize(ctx, details)
+	})
+}
+
+func (r *RoutingProcessor) Capture(ctx context.Context, details gateway.CaptureDetails) (*gateway.TransactionStatus, error) {
+	p, _, err := r.locate(ctx, details.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Capture(ctx, details)
+}
+
+func (r *RoutingProcessor) Void(ctx context.Context, details gateway.VoidDetails) (*gateway.TransactionStatus, error) {
+	p, _, err := r.locate(ctx, details.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return p.Void(ctx, details)
+}
+
+func (r *RoutingProcessor) MakeRefund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error) {
+	p, _, err := r.locate(ctx, details.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	return p.MakeRefund(ctx, details)
+}
+
+func (r *RoutingProcessor) CheckStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error) {
+	p, status, err := r.locate(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if status != nil {
+		return status, nil
+	}
+	return p.CheckStatus(ctx, transactionID)
+}
+
+func (r *RoutingProcessor) GetHistory(ctx context.Context, transactionID string) ([]gateway.TransactionEvent, error) {
+	p, _, err := r.locate(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetHistory(ctx, transactionID)
+}
+
+func (r *RoutingProcessor) List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error) {
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		merged []*gateway.TransactionStatus
+		more   bool
+	)
+
+	for _, route := range r.routes {
+		if query.Filter.Provider != "" && query.Filter.Provider != route.Name {
+			continue
+		}
+
+		page, err := route.Processor.List(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", route.Name, err)
+		}
+
+		merged = append(merged, page.Transactions...)
+		more = more || page.NextCursor != ""
+	}
+
+	slices.SortFunc(merged, query.Compare)
+
+	return query.Page(merged, more), nil
+}
+
+func (r *RoutingProcessor) Via(providers ...string) (*RoutingProcessor, error) {
+	routes := make([]Route, 0, len(providers))
+	for _, name := range providers {
+		p, ok := r.Processor(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not routable", ErrNoRoute, name)
+		}
+		routes = append(routes, Route{Name: name, Metadata: r.metadata(name), Processor: p})
+	}
+
+	return &RoutingProcessor{
+		routes:      routes,
+		providers:   r.providers,
+		failover:    r.failover,
+		assignments: r.assignments,
+	}, nil
+}
+
+func (r *RoutingProcessor) Provider(transactionID string) (string, bool) {
+	return r.assigned(assignment{id: transactionID})
+}
+
+func (r *RoutingProcessor) Processor(name string) (*Processor, bool) {
+	for _, route := range r.providers {
+		if route.Name == name {
+			return route.Processor, true
+		}
+	}
+	return nil, false
+}
+
+func (r *RoutingProcessor) metadata(name string) factory.ProviderMetadata {
+	for _, route := range r.providers {
+		if route.Name == name {
+			return route.Metadata
+		}
+	}
+	return factory.ProviderMetadata{}
+}
+
+func (r *RoutingProcessor) route(ctx context.Context, currency string, key string, fingerprint string, call func(*Processor) (*gateway.TransactionStatus, error)) (*gateway.TransactionStatus, error) {
+	if replayed, ok, err := r.replay(ctx, key, fingerprint); err != nil || ok {
+		return replayed, err
+	}
+
+	var lastErr error
+
+	for _, route := range r.routes {
+		if !route.Metadata.SupportsCurrency(currency) {
+			continue
+		}
+
+		status, err := call(route.Processor)
+		if err == nil {
+			r.assign(route.Name, status.TransactionID, key)
+			return status, nil
+		}
+
+		lastErr = fmt.Errorf("provider %s: %w", route.Name, err)
+
+		if !r.failover(err) || ctx.Err() != nil {
+			return nil, lastErr
+		}
+	}
+
+	if lastErr == nil {
+		return nil, gateway.Errorf(gateway.KindInvalidRequest, "", "%w: no provider supports currency %s", ErrNoRoute, currency)
+	}
+
+	return nil, lastErr
+}
+
+func (r *RoutingProcessor) replay(ctx context.Context, key string, fingerprint string) (*gateway.TransactionStatus, bool, error) {
+	if key == "" {
+		return nil, false, nil
+	}
+
+	if owner, ok := r.assigned(assignment{idempotencyKey: true, id: key}); ok {
+		if p, ok := r.Processor(owner); ok {
+			status, err := p.FindByIdempotencyKey(ctx, key, fingerprint)
+			switch {
+			case err == nil:
+				return status, true, nil
+			case errors.Is(err, gateway.ErrIdempotencyKeyNotFound):
+				return nil, false, nil
+			default:
+				return nil, false, fmt.Errorf("provider %s: %w", owner, err)
+			}
+		}
+	}
+
+	for _, route := range r.providers {
+		status, err := route.Processor.FindByIdempotencyKey(ctx, key, fingerprint)
+		if err == nil {
+			r.assign(route.Name, status.TransactionID, key)
+			return status, true, nil
+		}
+
+		if errors.Is(err, gateway.ErrIdempotencyKeyReused) || ctx.Err() != nil {
+			return nil, false, fmt.Errorf("provider %s: %w", route.Name, err)
+		}
+	}
+
+	return nil, false, nil
+}
+
+func (r *RoutingProcessor) locate(ctx context.Context, transactionID string) (*Processor, *gateway.TransactionStatus, error) {
+	if name, ok := r.Provider(transactionID); ok {
+		if p, ok := r.Processor(name); ok {
+			return p, nil, nil
+		}
+	}
+
+	var probeErr error
+
+	for _, route := range r.providers {
+		status, err := route.Processor.CheckStatus(ctx, transactionID)
+		if err == nil {
+			name := route.Name
+			if _, ok := r.Processor(status.Provider); ok {
+				name = status.Provider
+			}
+
+			r.assign(name, transactionID, "")
+
+			p, _ := r.Processor(name)
+			return p, status, nil
+		}
+
+		if errors.Is(err, gateway.ErrNotFound) {
+			continue
+		}
+
+		err = fmt.Errorf("provider %s: %w", route.Name, err)
+		if !errors.Is(err, gateway.ErrTransient) || ctx.Err() != nil {
+			return nil, nil, err
+		}
+		if probeErr == nil {
+			probeErr = err
+		}
+	}
+
+	if probeErr != nil {
+		return nil, nil, probeErr
+	}
+
+	return nil, nil, gateway.NewError(gateway.KindNotFound, "", "transaction not found")
+}
+
+func (r *RoutingProcessor) assigned(a assignment) (string, bool) {
+	r.assignments.mu.RLock()
+	defer r.assignments.mu.RUnlock()
+
+	name, ok := r.assignments.providers[a]
+	return name, ok
+}
+
+func (r *RoutingProcessor) assign(provider string, transactionID string, key string) {
+	r.assignments.mu.Lock()
+	defer r.assignments.mu.Unlock()
+
+	entries := []assignment{{id: transactionID}}
+	if key != "" {
+		entries = append(entries, assignment{idempotencyKey: true, id: key})
+	}
+
+	for _, a := range entries {
+		if _, ok := r.assignments.providers[a]; !ok {
+			r.assignments.order = append(r.assignments.order, a)
+		}
+		r.assignments.providers[a] = provider
+	}
+
+	for len(r.assignments.order) > r.assignments.limit {
+		delete(r.assignments.providers, r.assignments.order[0])
+		r.assignments.order = r.assignments.order[1:]
+	}
+}
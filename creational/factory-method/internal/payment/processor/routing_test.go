@@ -0,0 +1,306 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"factory-method/internal/payment/factory"
+	"factory-method/internal/payment/gateway"
+	"factory-method/internal/payment/gateway/engine"
+	"factory-method/pkg/money"
+)
+
+type gatewayFactory struct {
+	gateway gateway.PaymentGateway
+}
+
+func (f gatewayFactory) GetPaymentGateway() (gateway.PaymentGateway, error) {
+	return f.gateway, nil
+}
+
+func newTestRoute(t *testing.T, name string, faults gateway.FaultConfig) (Route, *engine.InMemoryTransactionStore) {
+	t.Helper()
+
+	store := engine.NewInMemoryTransactionStore(name,
+		engine.WithFaultInjector(gateway.NewFaultInjector(faults)),
+		engine.WithRetryPolicy(engine.NoRetryPolicy()),
+	)
+
+	provider := engine.Provider{Name: name, ValidationRules: engine.DefaultValidationRules()}
+
+	route, err := NewRoute(factory.Provider{
+		Name:    name,
+		Factory: gatewayFactory{gateway: engine.NewGateway(provider, store)},
+	})
+	if err != nil {
+		t.Fatalf("NewRoute(%s): %v", name, err)
+	}
+
+	return route, store
+}
+
+func testPayment(key string) gateway.PaymentDetails {
+	return gateway.PaymentDetails{
+		IdempotencyKey: key,
+		Amount:         money.New(1000, "USD"),
+		CardNumber:     "4242424242424242",
+		CardHolder:     "Jane Doe",
+		ExpiryDate:     "12/40",
+		CVV:            "123",
+	}
+}
+
+func countTransactions(t *testing.T, store *engine.InMemoryTransactionStore) int {
+	t.Helper()
+
+	page, err := store.List(context.Background(), gateway.ListQuery{Limit: gateway.MaxListLimit})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	return len(page.Transactions)
+}
+
+func TestRoutingProcessorReplaysIdempotentRetryAfterFailover(t *testing.T) {
+	primary, primaryStore := newTestRoute(t, "primary", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpSave: {true}},
+	})
+	secondary, secondaryStore := newTestRoute(t, "secondary", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{primary, secondary})
+	ctx := context.Background()
+
+	first, err := router.MakePayment(ctx, testPayment("order-1"))
+	if err != nil {
+		t.Fatalf("first MakePayment: %v", err)
+	}
+	if first.Provider != "secondary" {
+		t.Fatalf("first payment provider = %q, want secondary", first.Provider)
+	}
+
+	retried, err := router.MakePayment(ctx, testPayment("order-1"))
+	if err != nil {
+		t.Fatalf("retried MakePayment: %v", err)
+	}
+	if retried.TransactionID != first.TransactionID {
+		t.Fatalf("retry created transaction %s, want replay of %s", retried.TransactionID, first.TransactionID)
+	}
+
+	if n := countTransactions(t, primaryStore); n != 0 {
+		t.Fatalf("primary holds %d transactions, want 0", n)
+	}
+	if n := countTransactions(t, secondaryStore); n != 1 {
+		t.Fatalf("secondary holds %d transactions, want 1", n)
+	}
+}
+
+func TestRoutingProcessorRejectsReusedKeyAfterFailover(t *testing.T) {
+	primary, _ := newTestRoute(t, "primary", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpSave: {true}},
+	})
+	secondary, _ := newTestRoute(t, "secondary", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{primary, secondary})
+	ctx := context.Background()
+
+	if _, err := router.MakePayment(ctx, testPayment("order-2")); err != nil {
+		t.Fatalf("first MakePayment: %v", err)
+	}
+
+	changed := testPayment("order-2")
+	changed.Amount = money.New(2000, "USD")
+
+	_, err := router.MakePayment(ctx, changed)
+	if !errors.Is(err, gateway.ErrIdempotencyKeyReused) {
+		t.Fatalf("MakePayment with reused key = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestRoutingProcessorViaReplaysAcrossAllProviders(t *testing.T) {
+	primary, _ := newTestRoute(t, "primary", gateway.DisabledFaultConfig())
+	secondary, _ := newTestRoute(t, "secondary", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{primary, secondary})
+	ctx := context.Background()
+
+	viaSecondary, err := router.Via("secondary")
+	if err != nil {
+		t.Fatalf("Via: %v", err)
+	}
+
+	first, err := viaSecondary.MakePayment(ctx, testPayment("order-3"))
+	if err != nil {
+		t.Fatalf("first MakePayment: %v", err)
+	}
+
+	viaPrimary, err := router.Via("primary", "secondary")
+	if err != nil {
+		t.Fatalf("Via: %v", err)
+	}
+
+	retried, err := viaPrimary.MakePayment(ctx, testPayment("order-3"))
+	if err != nil {
+		t.Fatalf("retried MakePayment: %v", err)
+	}
+	if retried.TransactionID != first.TransactionID || retried.Provider != "secondary" {
+		t.Fatalf("retry returned %s on %s, want replay of %s on secondary", retried.TransactionID, retried.Provider, first.TransactionID)
+	}
+}
+
+func TestRoutingProcessorLocatesByPersistedProvider(t *testing.T) {
+	primary, _ := newTestRoute(t, "primary", gateway.DisabledFaultConfig())
+	secondary, _ := newTestRoute(t, "secondary", gateway.DisabledFaultConfig())
+	ctx := context.Background()
+
+	created, err := NewRoutingProcessor([]Route{secondary}).MakePayment(ctx, testPayment(""))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+
+	router := NewRoutingProcessor([]Route{primary, secondary})
+
+	status, err := router.CheckStatus(ctx, created.TransactionID)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if status.Provider != "secondary" {
+		t.Fatalf("CheckStatus provider = %q, want secondary", status.Provider)
+	}
+
+	refunded, err := router.MakeRefund(ctx, gateway.RefundDetails{
+		TransactionID: created.TransactionID,
+		Amount:        money.New(400, "USD"),
+	})
+	if err != nil {
+		t.Fatalf("MakeRefund: %v", err)
+	}
+	if refunded.Status != gateway.StatusPartiallyRefunded {
+		t.Fatalf("refund status = %q, want %q", refunded.Status, gateway.StatusPartiallyRefunded)
+	}
+
+	if _, err := router.CheckStatus(ctx, "missing"); !errors.Is(err, gateway.ErrNotFound) {
+		t.Fatalf("CheckStatus(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRoutingProcessorSendsFollowUpsToStickyProvider(t *testing.T) {
+	flaky, flakyStore := newTestRoute(t, "flaky", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpGet: {true}},
+	})
+	stable, _ := newTestRoute(t, "stable", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{flaky, stable})
+	ctx := context.Background()
+
+	viaStable, err := router.Via("stable")
+	if err != nil {
+		t.Fatalf("Via: %v", err)
+	}
+
+	created, err := viaStable.MakePayment(ctx, testPayment(""))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+
+	if name, ok := router.Provider(created.TransactionID); !ok || name != "stable" {
+		t.Fatalf("Provider = %q, %v, want stable", name, ok)
+	}
+
+	if _, err := router.MakeRefund(ctx, gateway.RefundDetails{
+		TransactionID: created.TransactionID,
+		Amount:        money.New(100, "USD"),
+	}); err != nil {
+		t.Fatalf("MakeRefund: %v", err)
+	}
+
+	if _, err := flakyStore.Get(ctx, created.TransactionID); !errors.Is(err, gateway.ErrTransient) {
+		t.Fatalf("flaky store Get = %v, want its scripted fault still pending", err)
+	}
+}
+
+func TestRoutingProcessorLocateSkipsTransientProbeErrors(t *testing.T) {
+	flaky, _ := newTestRoute(t, "flaky", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpGet: {true}},
+	})
+	stable, _ := newTestRoute(t, "stable", gateway.DisabledFaultConfig())
+	ctx := context.Background()
+
+	created, err := NewRoutingProcessor([]Route{stable}).MakePayment(ctx, testPayment(""))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+
+	status, err := NewRoutingProcessor([]Route{flaky, stable}).CheckStatus(ctx, created.TransactionID)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if status.Provider != "stable" {
+		t.Fatalf("CheckStatus provider = %q, want stable", status.Provider)
+	}
+}
+
+func TestRoutingProcessorBoundsAssignments(t *testing.T) {
+	route, _ := newTestRoute(t, "primary", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{route}, WithAssignmentLimit(1))
+	ctx := context.Background()
+
+	first, err := router.MakePayment(ctx, testPayment(""))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+	second, err := router.MakePayment(ctx, testPayment(""))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+
+	if _, ok := router.Provider(first.TransactionID); ok {
+		t.Fatal("oldest assignment was not evicted")
+	}
+	if _, ok := router.Provider(second.TransactionID); !ok {
+		t.Fatal("newest assignment missing")
+	}
+
+	if _, err := router.CheckStatus(ctx, first.TransactionID); err != nil {
+		t.Fatalf("CheckStatus after eviction: %v", err)
+	}
+}
+
+func TestRoutingProcessorPaysWhenAnotherProviderLookupFails(t *testing.T) {
+	flaky, _ := newTestRoute(t, "flaky", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpFindByIdempotencyKey: {true}},
+	})
+	stable, _ := newTestRoute(t, "stable", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{flaky, stable})
+
+	if _, err := router.MakePayment(context.Background(), testPayment("order-4")); err != nil {
+		t.Fatalf("MakePayment with one lookup failing: %v", err)
+	}
+}
+
+func TestRoutingProcessorFailsWhenKeyOwnerIsUnreachable(t *testing.T) {
+	primary, _ := newTestRoute(t, "primary", gateway.FaultConfig{
+		Script: map[gateway.Operation][]bool{gateway.OpFindByIdempotencyKey: {false, false, true}},
+	})
+	secondary, secondaryStore := newTestRoute(t, "secondary", gateway.DisabledFaultConfig())
+
+	router := NewRoutingProcessor([]Route{primary, secondary})
+	ctx := context.Background()
+
+	first, err := router.MakePayment(ctx, testPayment("order-5"))
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+	if first.Provider != "primary" {
+		t.Fatalf("first payment provider = %q, want primary", first.Provider)
+	}
+
+	if _, err := router.MakePayment(ctx, testPayment("order-5")); !errors.Is(err, gateway.ErrTransient) {
+		t.Fatalf("retry while key owner is down = %v, want transient error", err)
+	}
+	if n := countTransactions(t, secondaryStore); n != 0 {
+		t.Fatalf("secondary holds %d transactions, want 0", n)
+	}
+}
@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"factory-method/internal/payment/factory"
+	"factory-method/internal/payment/gateway"
+)
+
+type RuleEngine struct {
+	rules     RuleSet
+	providers map[string]factory.Provider
+}
+
+func NewRuleEngine(rules RuleSet, registry *factory.Registry) (*RuleEngine, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]factory.Provider)
+	for _, name := range rules.providers() {
+		provider, err := registry.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRules, err)
+		}
+		providers[provider.Name] = provider
+	}
+
+	return &RuleEngine{
+		rules:     rules,
+		providers: providers,
+	}, nil
+}
+
+func (e *RuleEngine) Route(ctx context.Context, request Request) (Decision, error) {
+	if ctx.Err() != nil {
+		return Decision{}, ctx.Err()
+	}
+
+	decision := Decision{Rule: "default"}
+	names := e.rules.Default
+	prefer := e.rules.Prefer
+
+	for _, rule := range e.rules.Rules {
+		matched, reason := rule.When.match(request)
+		decision.Trace = append(decision.Trace, Step{Rule: rule.Name, Matched: matched, Reason: reason})
+
+		if matched {
+			decision.Rule = rule.Name
+			names = rule.Providers
+			prefer = rule.Prefer
+			break
+		}
+	}
+
+	currency := request.Amount.Currency()
+
+	for _, name := range names {
+		provider := e.providers[name]
+		if !provider.Metadata.SupportsCurrency(currency) {
+			decision.Excluded = append(decision.Excluded, Exclusion{
+				Provider: name,
+				Reason:   "currency " + currency + " is not supported",
+			})
+			continue
+		}
+
+		decision.Candidates = append(decision.Candidates, Candidate{
+			Provider:     name,
+			EstimatedFee: provider.Metadata.EstimateFee(request.Amount),
+		})
+	}
+
+	if prefer == PreferCheapest {
+		slices.SortStableFunc(decision.Candidates, func(a, b Candidate) int {
+			return cmp.Compare(a.EstimatedFee.Amount(), b.EstimatedFee.Amount())
+		})
+	}
+
+	if len(decision.Candidates) == 0 {
+		return decision, gateway.Errorf(gateway.KindInvalidRequest, "", "%w: rule %q has no provider supporting %s", ErrNoRoute, decision.Rule, currency)
+	}
+
+	return decision, nil
+}
@@ -0,0 +1,139 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"factory-method/internal/payment/card"
+	"factory-method/internal/payment/factory"
+	"factory-method/pkg/money"
+)
+
+func newTestEngine(t *testing.T, rules RuleSet) *RuleEngine {
+	t.Helper()
+
+	engine, err := NewRuleEngine(rules, factory.NewDefaultRegistry(factory.WithoutFaults()))
+	if err != nil {
+		t.Fatalf("NewRuleEngine: %v", err)
+	}
+
+	return engine
+}
+
+func TestRuleEngineExplainsDecision(t *testing.T) {
+	engine := newTestEngine(t, RuleSet{
+		Rules: []Rule{
+			{Name: "amex", When: Condition{Brands: []card.Brand{card.Amex}}, Providers: []string{factory.StripeProviderName}},
+			{Name: "large-usd", When: Condition{Currencies: []string{"USD"}, MinAmount: amount(100000)}, Providers: []string{factory.PaypalProviderName, factory.StripeProviderName}, Prefer: PreferCheapest},
+		},
+		Default: []string{factory.PaypalProviderName, factory.StripeProviderName},
+	})
+
+	tests := []struct {
+		name      string
+		request   Request
+		rule      string
+		matched   []bool
+		providers []string
+		excluded  []string
+	}{
+		{
+			name:      "first rule",
+			request:   Request{Amount: money.New(500, "USD"), Brand: card.Amex},
+			rule:      "amex",
+			matched:   []bool{true},
+			providers: []string{factory.StripeProviderName},
+		},
+		{
+			name:      "default",
+			request:   Request{Amount: money.New(500, "USD"), Brand: card.Visa},
+			rule:      "default",
+			matched:   []bool{false, false},
+			providers: []string{factory.PaypalProviderName, factory.StripeProviderName},
+		},
+		{
+			name:      "default excludes unsupported currency",
+			request:   Request{Amount: money.New(500, "BRL"), Brand: card.Visa},
+			rule:      "default",
+			matched:   []bool{false, false},
+			providers: []string{factory.StripeProviderName},
+			excluded:  []string{factory.PaypalProviderName},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := engine.Route(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+
+			if decision.Rule != tt.rule {
+				t.Fatalf("rule = %q, want %q", decision.Rule, tt.rule)
+			}
+
+			matched := make([]bool, 0, len(decision.Trace))
+			for _, step := range decision.Trace {
+				if step.Reason == "" {
+					t.Fatalf("trace step %q has no reason", step.Rule)
+				}
+				matched = append(matched, step.Matched)
+			}
+			if !slices.Equal(matched, tt.matched) {
+				t.Fatalf("trace matched = %v, want %v", matched, tt.matched)
+			}
+
+			if got := decision.Providers(); !slices.Equal(got, tt.providers) {
+				t.Fatalf("providers = %v, want %v", got, tt.providers)
+			}
+
+			var excluded []string
+			for _, e := range decision.Excluded {
+				excluded = append(excluded, e.Provider)
+			}
+			if !slices.Equal(excluded, tt.excluded) {
+				t.Fatalf("excluded = %v, want %v", excluded, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestRuleEnginePrefersCheapestProvider(t *testing.T) {
+	engine := newTestEngine(t, RuleSet{
+		Default: []string{factory.PaypalProviderName, factory.StripeProviderName},
+		Prefer:  PreferCheapest,
+	})
+
+	decision, err := engine.Route(context.Background(), Request{Amount: money.New(250000, "USD"), Brand: card.Visa})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+
+	if len(decision.Candidates) != 2 {
+		t.Fatalf("candidates = %v, want both providers", decision.Providers())
+	}
+	if first, second := decision.Candidates[0].EstimatedFee, decision.Candidates[1].EstimatedFee; first.Amount() > second.Amount() {
+		t.Fatalf("candidates ordered %s before %s, want cheapest first", first, second)
+	}
+}
+
+func TestRuleEngineReportsNoRoute(t *testing.T) {
+	engine := newTestEngine(t, RuleSet{Default: []string{factory.StripeProviderName}})
+
+	decision, err := engine.Route(context.Background(), Request{Amount: money.New(500, "CZK")})
+	if !errors.Is(err, ErrNoRoute) {
+		t.Fatalf("Route = %v, want ErrNoRoute", err)
+	}
+	if len(decision.Excluded) != 1 || decision.Excluded[0].Provider != factory.StripeProviderName {
+		t.Fatalf("excluded = %+v, want stripe", decision.Excluded)
+	}
+}
+
+func TestNewRuleEngineRejectsUnknownProviders(t *testing.T) {
+	_, err := NewRuleEngine(RuleSet{Default: []string{"acme"}}, factory.NewDefaultRegistry())
+	if !errors.Is(err, ErrInvalidRules) {
+		t.Fatalf("NewRuleEngine = %v, want ErrInvalidRules", err)
+	}
+}
@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"context"
+	"errors"
+
+	"factory-method/internal/payment/card"
+	"factory-method/pkg/money"
+)
+
+var ErrNoRoute = errors.New("no payment provider matches the request")
+
+type Request struct {
+	Amount money.Money
+	Brand  card.Brand
+}
+
+type Candidate struct {
+	Provider     string
+	EstimatedFee money.Money
+}
+
+type Exclusion struct {
+	Provider string
+	Reason   string
+}
+
+type Step struct {
+	Rule    string
+	Matched bool
+	Reason  string
+}
+
+type Decision struct {
+	Rule       string
+	Candidates []Candidate
+	Excluded   []Exclusion
+	Trace      []Step
+}
+
+func (d Decision) Providers() []string {
+	providers := make([]string, 0, len(d.Candidates))
+	for _, c := range d.Candidates {
+		providers = append(providers, c.Provider)
+	}
+	return providers
+}
+
+type Strategy interface {
+	Route(ctx context.Context, request Request) (Decision, error)
+}
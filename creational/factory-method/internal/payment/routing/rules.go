@@ -0,0 +1,155 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"factory-method/internal/payment/card"
+)
+
+var ErrInvalidRules = errors.New("invalid routing rules")
+
+type Preference string
+
+const (
+	PreferOrder    Preference = "order"
+	PreferCheapest Preference = "cheapest"
+)
+
+type Condition struct {
+	Currencies []string     `json:"currencies,omitempty"`
+	Brands     []card.Brand `json:"brands,omitempty"`
+	MinAmount  *int64       `json:"min_amount,omitempty"`
+	MaxAmount  *int64       `json:"max_amount,omitempty"`
+}
+
+type Rule struct {
+	Name      string     `json:"name"`
+	When      Condition  `json:"when"`
+	Providers []string   `json:"providers"`
+	Prefer    Preference `json:"prefer,omitempty"`
+}
+
+type RuleSet struct {
+	Rules   []Rule     `json:"rules"`
+	Default []string   `json:"default"`
+	Prefer  Preference `json:"prefer,omitempty"`
+}
+
+func LoadRules(r io.Reader) (RuleSet, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var rules RuleSet
+	if err := decoder.Decode(&rules); err != nil {
+		return RuleSet{}, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return RuleSet{}, err
+	}
+
+	return rules, nil
+}
+
+func LoadRulesFile(path string) (RuleSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return RuleSet{}, err
+	}
+	defer f.Close()
+
+	return LoadRules(f)
+}
+
+func (s RuleSet) Validate() error {
+	if len(s.Default) == 0 {
+		return fmt.Errorf("%w: default provider list is empty", ErrInvalidRules)
+	}
+	if !s.Prefer.valid() {
+		return fmt.Errorf("%w: default prefers unknown ordering %q", ErrInvalidRules, s.Prefer)
+	}
+
+	seen := make(map[string]bool, len(s.Rules))
+	for i, rule := range s.Rules {
+		switch {
+		case rule.Name == "":
+			return fmt.Errorf("%w: rule %d has no name", ErrInvalidRules, i)
+		case seen[rule.Name]:
+			return fmt.Errorf("%w: duplicate rule %q", ErrInvalidRules, rule.Name)
+		case len(rule.Providers) == 0:
+			return fmt.Errorf("%w: rule %q routes to no providers", ErrInvalidRules, rule.Name)
+		case !rule.Prefer.valid():
+			return fmt.Errorf("%w: rule %q prefers unknown ordering %q", ErrInvalidRules, rule.Name, rule.Prefer)
+		case rule.When.MinAmount != nil && rule.When.MaxAmount != nil && *rule.When.MinAmount > *rule.When.MaxAmount:
+			return fmt.Errorf("%w: rule %q has min_amount above max_amount", ErrInvalidRules, rule.Name)
+		case (rule.When.MinAmount != nil || rule.When.MaxAmount != nil) && len(rule.When.Currencies) != 1:
+			return fmt.Errorf("%w: rule %q bounds the amount without naming exactly one currency", ErrInvalidRules, rule.Name)
+		}
+		seen[rule.Name] = true
+	}
+
+	return nil
+}
+
+func (s RuleSet) providers() []string {
+	var providers []string
+	for _, rule := range s.Rules {
+		providers = append(providers, rule.Providers...)
+	}
+	providers = append(providers, s.Default...)
+
+	slices.Sort(providers)
+
+	return slices.Compact(providers)
+}
+
+func (p Preference) valid() bool {
+	return p == "" || p == PreferOrder || p == PreferCheapest
+}
+
+func (c Condition) match(request Request) (bool, string) {
+	var matched []string
+
+	if len(c.Currencies) > 0 {
+		currency := request.Amount.Currency()
+		if !slices.ContainsFunc(c.Currencies, func(v string) bool { return strings.EqualFold(v, currency) }) {
+			return false, fmt.Sprintf("currency %s not in %v", currency, c.Currencies)
+		}
+		matched = append(matched, "currency "+currency)
+	}
+
+	if len(c.Brands) > 0 {
+		if !slices.Contains(c.Brands, request.Brand) {
+			return false, fmt.Sprintf("card brand %s not in %v", request.Brand, c.Brands)
+		}
+		matched = append(matched, "card brand "+string(request.Brand))
+	}
+
+	amount := request.Amount.Amount()
+
+	if c.MinAmount != nil {
+		if amount < *c.MinAmount {
+			return false, fmt.Sprintf("amount %d below minimum %d", amount, *c.MinAmount)
+		}
+		matched = append(matched, fmt.Sprintf("amount %d >= %d", amount, *c.MinAmount))
+	}
+
+	if c.MaxAmount != nil {
+		if amount > *c.MaxAmount {
+			return false, fmt.Sprintf("amount %d above maximum %d", amount, *c.MaxAmount)
+		}
+		matched = append(matched, fmt.Sprintf("amount %d <= %d", amount, *c.MaxAmount))
+	}
+
+	if len(matched) == 0 {
+		return true, "rule has no conditions"
+	}
+
+	return true, strings.Join(matched, ", ")
+}
@@ -0,0 +1,85 @@
+package routing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"factory-method/internal/payment/card"
+	"factory-method/pkg/money"
+)
+
+func amount(v int64) *int64 {
+	return &v
+}
+
+func TestLoadRulesValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+		want  string
+	}{
+		{
+			name:  "valid",
+			rules: `{"rules":[{"name":"large-usd","when":{"currencies":["USD"],"min_amount":100000},"providers":["stripe"],"prefer":"cheapest"}],"default":["paypal"]}`,
+		},
+		{name: "malformed", rules: `{"rules":`, want: "unexpected EOF"},
+		{name: "unknown field", rules: `{"default":["stripe"],"fallback":["paypal"]}`, want: "unknown field"},
+		{name: "no default", rules: `{"rules":[]}`, want: "default provider list is empty"},
+		{name: "unknown default preference", rules: `{"default":["stripe"],"prefer":"fastest"}`, want: "default prefers unknown ordering"},
+		{name: "unnamed rule", rules: `{"rules":[{"providers":["stripe"]}],"default":["stripe"]}`, want: "rule 0 has no name"},
+		{name: "duplicate rule", rules: `{"rules":[{"name":"a","providers":["stripe"]},{"name":"a","providers":["paypal"]}],"default":["stripe"]}`, want: `duplicate rule "a"`},
+		{name: "no providers", rules: `{"rules":[{"name":"a"}],"default":["stripe"]}`, want: "routes to no providers"},
+		{name: "unknown rule preference", rules: `{"rules":[{"name":"a","providers":["stripe"],"prefer":"fastest"}],"default":["stripe"]}`, want: "prefers unknown ordering"},
+		{name: "inverted bounds", rules: `{"rules":[{"name":"a","when":{"currencies":["USD"],"min_amount":500,"max_amount":100},"providers":["stripe"]}],"default":["stripe"]}`, want: "min_amount above max_amount"},
+		{name: "bounds without currency", rules: `{"rules":[{"name":"a","when":{"min_amount":500},"providers":["stripe"]}],"default":["stripe"]}`, want: "without naming exactly one currency"},
+		{name: "bounds across currencies", rules: `{"rules":[{"name":"a","when":{"currencies":["USD","JPY"],"max_amount":500},"providers":["stripe"]}],"default":["stripe"]}`, want: "without naming exactly one currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRules(strings.NewReader(tt.rules))
+
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("LoadRules: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidRules) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadRules = %v, want ErrInvalidRules containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    Condition
+		request Request
+		want    bool
+		reason  string
+	}{
+		{name: "empty", want: true, reason: "rule has no conditions", request: Request{Amount: money.New(100, "USD")}},
+		{name: "currency", when: Condition{Currencies: []string{"usd"}}, request: Request{Amount: money.New(100, "USD")}, want: true, reason: "currency USD"},
+		{name: "other currency", when: Condition{Currencies: []string{"EUR"}}, request: Request{Amount: money.New(100, "USD")}, reason: "currency USD not in [EUR]"},
+		{name: "brand", when: Condition{Brands: []card.Brand{card.Amex}}, request: Request{Amount: money.New(100, "USD"), Brand: card.Amex}, want: true, reason: "card brand amex"},
+		{name: "other brand", when: Condition{Brands: []card.Brand{card.Amex}}, request: Request{Amount: money.New(100, "USD"), Brand: card.Visa}, reason: "card brand visa not in [amex]"},
+		{name: "at minimum", when: Condition{Currencies: []string{"USD"}, MinAmount: amount(100)}, request: Request{Amount: money.New(100, "USD")}, want: true, reason: "currency USD, amount 100 >= 100"},
+		{name: "below minimum", when: Condition{Currencies: []string{"USD"}, MinAmount: amount(101)}, request: Request{Amount: money.New(100, "USD")}, reason: "amount 100 below minimum 101"},
+		{name: "at maximum", when: Condition{Currencies: []string{"USD"}, MaxAmount: amount(100)}, request: Request{Amount: money.New(100, "USD")}, want: true, reason: "currency USD, amount 100 <= 100"},
+		{name: "above maximum", when: Condition{Currencies: []string{"USD"}, MaxAmount: amount(99)}, request: Request{Amount: money.New(100, "USD")}, reason: "amount 100 above maximum 99"},
+		{name: "bounds skip other currencies", when: Condition{Currencies: []string{"USD"}, MaxAmount: amount(1000)}, request: Request{Amount: money.New(100, "JPY")}, reason: "currency JPY not in [USD]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, reason := tt.when.match(tt.request)
+			if matched != tt.want || reason != tt.reason {
+				t.Fatalf("match = %t, %q, want %t, %q", matched, reason, tt.want, tt.reason)
+			}
+		})
+	}
+}
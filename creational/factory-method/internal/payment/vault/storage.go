@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"context"
+	"sync"
+)
+
+type Record struct {
+	Token      Token
+	Index      string
+	Ciphertext []byte
+}
+
+type Storage interface {
+	Put(ctx context.Context, record Record) error
+	Get(ctx context.Context, tokenID string) (Record, error)
+	FindByIndex(ctx context.Context, index string) (Record, error)
+	Delete(ctx context.Context, tokenID string) error
+}
+
+type MemoryStorage struct {
+	records map[string]Record
+	index   map[string]string
+	mu      sync.RWMutex
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		records: make(map[string]Record),
+		index:   make(map[string]string),
+	}
+}
+
+func (s *MemoryStorage) Put(ctx context.Context, record Record) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record.Ciphertext = append([]byte(nil), record.Ciphertext...)
+	s.records[record.Token.ID] = record
+	s.index[record.Index] = record.Token.ID
+
+	return nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, tokenID string) (Record, error) {
+	if ctx.Err() != nil {
+		return Record{}, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[tokenID]
+	if !ok {
+		return Record{}, ErrTokenNotFound
+	}
+
+	return record, nil
+}
+
+func (s *MemoryStorage) FindByIndex(ctx context.Context, index string) (Record, error) {
+	if ctx.Err() != nil {
+		return Record{}, ctx.Err()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokenID, ok := s.index[index]
+	if !ok {
+		return Record{}, ErrTokenNotFound
+	}
+
+	return s.records[tokenID], nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, tokenID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[tokenID]
+	if !ok {
+		return ErrTokenNotFound
+	}
+
+	delete(s.records, tokenID)
+	delete(s.index, record.Index)
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package vault
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"factory-method/internal/payment/card"
+)
+
+const (
+	KeySize     = 32
+	TokenPrefix = "tok_"
+)
+
+var (
+	ErrTokenNotFound = errors.New("vault: card token not found")
+	ErrInvalidKey    = errors.New("vault: encryption key must be 32 bytes")
+	ErrInvalidCard   = errors.New("vault: card number must contain only digits")
+)
+
+type Token struct {
+	ID         string
+	Brand      card.Brand
+	Last4      string
+	ExpiryDate string
+	CreatedAt  time.Time
+}
+
+type sealedCard struct {
+	Number string `json:"number"`
+	Holder string `json:"holder"`
+	Expiry string `json:"expiry"`
+}
+
+type Vault struct {
+	storage  Storage
+	aead     cipher.AEAD
+	indexKey []byte
+	now      func() time.Time
+}
+
+type Option func(*Vault)
+
+func WithStorage(storage Storage) Option {
+	return func(v *Vault) {
+		v.storage = storage
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(v *Vault) {
+		if now != nil {
+			v.now = now
+		}
+	}
+}
+
+func New(key []byte, opts ...Option) (*Vault, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(deriveKey(key, "encryption"))
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+
+	v := &Vault{
+		storage:  NewMemoryStorage(),
+		aead:     aead,
+		indexKey: deriveKey(key, "index"),
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v, nil
+}
+
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	return key, nil
+}
+
+func ParseKey(value string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+func IsToken(value string) bool {
+	return strings.HasPrefix(value, TokenPrefix)
+}
+
+func (v *Vault) Tokenize(ctx context.Context, c card.Card) (Token, error) {
+	number := card.Normalize(c.Number)
+	if !card.IsDigits(number) {
+		return Token{}, ErrInvalidCard
+	}
+
+	holder := strings.TrimSpace(c.Holder)
+	expiry := strings.TrimSpace(c.Expiry)
+	index := v.indexFor(number, holder, expiry)
+
+	existing, err := v.storage.FindByIndex(ctx, index)
+	switch {
+	case err == nil:
+		return existing.Token, nil
+	case !errors.Is(err, ErrTokenNotFound):
+		return Token{}, err
+	}
+
+	token := Token{
+		Brand:      card.DetectBrand(number),
+		Last4:      number[max(0, len(number)-4):],
+		ExpiryDate: expiry,
+		CreatedAt:  v.now(),
+	}
+	if token.ID, err = newTokenID(); err != nil {
+		return Token{}, err
+	}
+
+	ciphertext, err := v.seal(token.ID, sealedCard{Number: number, Holder: holder, Expiry: expiry})
+	if err != nil {
+		return Token{}, err
+	}
+
+	if err := v.storage.Put(ctx, Record{Token: token, Index: index, Ciphertext: ciphertext}); err != nil {
+		return Token{}, fmt.Errorf("vault: store token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (v *Vault) Lookup(ctx context.Context, tokenID string) (Token, error) {
+	record, err := v.storage.Get(ctx, tokenID)
+	if err != nil {
+		return Token{}, err
+	}
+	return record.Token, nil
+}
+
+func (v *Vault) Detokenize(ctx context.Context, tokenID string) (card.Card, error) {
+	record, err := v.storage.Get(ctx, tokenID)
+	if err != nil {
+		return card.Card{}, err
+	}
+
+	sealed, err := v.open(tokenID, record.Ciphertext)
+	if err != nil {
+		return card.Card{}, err
+	}
+
+	return card.Card{
+		Number: sealed.Number,
+		Holder: sealed.Holder,
+		Expiry: sealed.Expiry,
+	}, nil
+}
+
+func (v *Vault) Delete(ctx context.Context, tokenID string) error {
+	return v.storage.Delete(ctx, tokenID)
+}
+
+func (v *Vault) seal(tokenID string, c sealedCard) ([]byte, error) {
+	plaintext, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+
+	return v.aead.Seal(nonce, nonce, plaintext, []byte(tokenID)), nil
+}
+
+func (v *Vault) open(tokenID string, ciphertext []byte) (sealedCard, error) {
+	nonceSize := v.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return sealedCard{}, errors.New("vault: ciphertext is truncated")
+	}
+
+	plaintext, err := v.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], []byte(tokenID))
+	if err != nil {
+		return sealedCard{}, fmt.Errorf("vault: decrypt card: %w", err)
+	}
+
+	var c sealedCard
+	if err := json.Unmarshal(plaintext, &c); err != nil {
+		return sealedCard{}, fmt.Errorf("vault: %w", err)
+	}
+
+	return c, nil
+}
+
+func (v *Vault) indexFor(number, holder, expiry string) string {
+	mac := hmac.New(sha256.New, v.indexKey)
+	for _, part := range []string{number, holder, expiry} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("vault: %w", err)
+	}
+	return TokenPrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	"factory-method/internal/payment/card"
+)
+
+func newTestVault(t *testing.T) *Vault {
+	t.Helper()
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	v, err := New(key)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return v
+}
+
+func TestTokenizeSameCardReturnsSameToken(t *testing.T) {
+	v := newTestVault(t)
+	ctx := context.Background()
+
+	c := card.Card{Number: "4242 4242 4242 4242", Holder: "Jane Doe", Expiry: "12/40"}
+
+	first, err := v.Tokenize(ctx, c)
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+
+	second, err := v.Tokenize(ctx, c)
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+
+	if second != first {
+		t.Fatalf("second token = %+v, want %+v", second, first)
+	}
+}
+
+func TestTokenizeNeverRewritesExistingToken(t *testing.T) {
+	v := newTestVault(t)
+	ctx := context.Background()
+
+	original := card.Card{Number: "4242424242424242", Holder: "Jane Doe", Expiry: "12/40"}
+	renewed := card.Card{Number: "4242424242424242", Holder: "Jane Doe", Expiry: "06/45"}
+
+	first, err := v.Tokenize(ctx, original)
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+
+	second, err := v.Tokenize(ctx, renewed)
+	if err != nil {
+		t.Fatalf("Tokenize: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatalf("renewed card reused token %s", first.ID)
+	}
+
+	for _, tc := range []struct {
+		token string
+		want  card.Card
+	}{
+		{token: first.ID, want: original},
+		{token: second.ID, want: renewed},
+	} {
+		got, err := v.Detokenize(ctx, tc.token)
+		if err != nil {
+			t.Fatalf("Detokenize(%s): %v", tc.token, err)
+		}
+		if got.Holder != tc.want.Holder || got.Expiry != tc.want.Expiry {
+			t.Fatalf("Detokenize(%s) = %s %s, want %s %s", tc.token, got.Holder, got.Expiry, tc.want.Holder, tc.want.Expiry)
+		}
+	}
+}
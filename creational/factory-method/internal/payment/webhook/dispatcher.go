@@ -0,0 +1,466 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+const maxErrorBodyBytes = 512
+
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  6,
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Minute,
+		Multiplier:   3,
+		Jitter:       0.2,
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(p.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= p.Multiplier
+		if p.MaxDelay > 0 && delay >= float64(p.MaxDelay) {
+			delay = float64(p.MaxDelay)
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+type Dispatcher struct {
+	client     *http.Client
+	workers    int
+	retry      RetryPolicy
+	now        func() time.Time
+	queue      chan string
+	endpoints  map[string]*Endpoint
+	deliveries map[string]*Delivery
+	timers     map[string]*time.Timer
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	started    bool
+	closed     bool
+	mu         sync.Mutex
+}
+
+type Option func(*Dispatcher)
+
+func WithWorkers(workers int) Option {
+	return func(d *Dispatcher) {
+		if workers > 0 {
+			d.workers = workers
+		}
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(d *Dispatcher) {
+		d.retry = policy
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		if client != nil {
+			d.client = client
+		}
+	}
+}
+
+func WithQueueSize(size int) Option {
+	return func(d *Dispatcher) {
+		if size > 0 {
+			d.queue = make(chan string, size)
+		}
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(d *Dispatcher) {
+		if now != nil {
+			d.now = now
+		}
+	}
+}
+
+func NewDispatcher(opts ...Option) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Dispatcher{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		workers:    4,
+		retry:      DefaultRetryPolicy(),
+		now:        func() time.Time { return time.Now().UTC() },
+		queue:      make(chan string, 1024),
+		endpoints:  make(map[string]*Endpoint),
+		deliveries: make(map[string]*Delivery),
+		timers:     make(map[string]*time.Timer),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.started || d.closed {
+		return
+	}
+	d.started = true
+
+	for range d.workers {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	for id, timer := range d.timers {
+		timer.Stop()
+		delete(d.timers, id)
+	}
+	d.mu.Unlock()
+
+	d.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) Register(rawURL string, secret string, types ...EventType) (Endpoint, error) {
+	if err := validateEndpoint(rawURL, types); err != nil {
+		return Endpoint{}, err
+	}
+
+	if secret == "" {
+		generated, err := generateSecret()
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("webhook: generate secret: %w", err)
+		}
+		secret = generated
+	}
+
+	endpoint := &Endpoint{
+		ID:         generateID("we_"),
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: slices.Clone(types),
+		CreatedAt:  d.now(),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.endpoints[endpoint.ID] = endpoint
+
+	return *endpoint, nil
+}
+
+func (d *Dispatcher) Unregister(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.endpoints[id]; !ok {
+		return fmt.Errorf("%w: %q", ErrEndpointNotFound, id)
+	}
+
+	delete(d.endpoints, id)
+
+	return nil
+}
+
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]Endpoint, 0, len(d.endpoints))
+	for _, endpoint := range d.endpoints {
+		endpoints = append(endpoints, *endpoint)
+	}
+
+	slices.SortFunc(endpoints, func(a, b Endpoint) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return endpoints
+}
+
+func (d *Dispatcher) Publish(commit gateway.Commit) {
+	events := eventsFromCommit(commit)
+	if len(events) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	now := d.now()
+
+	for _, event := range events {
+		for _, endpoint := range d.endpoints {
+			if !endpoint.subscribes(event.Type) {
+				continue
+			}
+
+			delivery := &Delivery{
+				ID:            generateID("whd_"),
+				EndpointID:    endpoint.ID,
+				Event:         event,
+				State:         DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+				UpdatedAt:     now,
+			}
+
+			d.deliveries[delivery.ID] = delivery
+			d.enqueueLocked(delivery.ID)
+		}
+	}
+}
+
+func (d *Dispatcher) Delivery(id string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrDeliveryNotFound, id)
+	}
+
+	return *delivery, nil
+}
+
+func (d *Dispatcher) Pending() []Delivery {
+	return d.deliveriesIn(DeliveryPending)
+}
+
+func (d *Dispatcher) DeadLetters() []Delivery {
+	return d.deliveriesIn(DeliveryDead)
+}
+
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delivery, ok := d.deliveries[id]
+	if !ok {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrDeliveryNotFound, id)
+	}
+
+	if delivery.State != DeliveryDead {
+		return Delivery{}, fmt.Errorf("%w: %q is %s", ErrDeliveryNotDead, id, delivery.State)
+	}
+
+	if _, ok := d.endpoints[delivery.EndpointID]; !ok {
+		return Delivery{}, fmt.Errorf("%w: %q", ErrEndpointNotFound, delivery.EndpointID)
+	}
+
+	if timer, ok := d.timers[id]; ok {
+		timer.Stop()
+		delete(d.timers, id)
+	}
+
+	now := d.now()
+	delivery.State = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+
+	d.enqueueLocked(id)
+
+	return *delivery, nil
+}
+
+func (d *Dispatcher) deliveriesIn(state DeliveryState) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var deliveries []Delivery
+	for _, delivery := range d.deliveries {
+		if delivery.State == state {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+
+	slices.SortFunc(deliveries, func(a, b Delivery) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return deliveries
+}
+
+func (d *Dispatcher) enqueueLocked(id string) {
+	if d.closed {
+		return
+	}
+
+	select {
+	case d.queue <- id:
+	default:
+		d.scheduleLocked(id, d.retry.InitialDelay)
+	}
+}
+
+func (d *Dispatcher) scheduleLocked(id string, delay time.Duration) {
+	d.timers[id] = time.AfterFunc(delay, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		delete(d.timers, id)
+		d.enqueueLocked(id)
+	})
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case id := <-d.queue:
+			d.deliver(id)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(id string) {
+	d.mu.Lock()
+	delivery, ok := d.deliveries[id]
+	if !ok || delivery.State != DeliveryPending {
+		d.mu.Unlock()
+		return
+	}
+
+	endpoint, ok := d.endpoints[delivery.EndpointID]
+	if !ok {
+		delivery.State = DeliveryDead
+		delivery.LastError = ErrEndpointNotFound.Error()
+		delivery.UpdatedAt = d.now()
+		d.mu.Unlock()
+		return
+	}
+
+	delivery.Attempts++
+	attempt := delivery.Attempts
+	event := delivery.Event
+	target := *endpoint
+	d.mu.Unlock()
+
+	statusCode, err := d.send(target, id, event)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	delivery.LastStatusCode = statusCode
+	delivery.UpdatedAt = now
+
+	switch {
+	case err == nil:
+		delivery.State = DeliveryDelivered
+		delivery.LastError = ""
+		delete(d.deliveries, id)
+	case d.closed:
+		delivery.LastError = err.Error()
+	case attempt >= d.retry.MaxAttempts:
+		delivery.State = DeliveryDead
+		delivery.LastError = err.Error()
+	default:
+		delay := d.retry.backoff(attempt)
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(delay)
+		d.scheduleLocked(id, delay)
+	}
+}
+
+func (d *Dispatcher) send(endpoint Endpoint, deliveryID string, event Event) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, d.now(), payload))
+	req.Header.Set(EventIDHeader, event.ID)
+	req.Header.Set(EventTypeHeader, string(event.Type))
+	req.Header.Set(DeliveryIDHeader, deliveryID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	message := fmt.Sprintf("endpoint responded with status %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(body)); text != "" {
+		message += ": " + text
+	}
+
+	return resp.StatusCode, fmt.Errorf("%s", message)
+}
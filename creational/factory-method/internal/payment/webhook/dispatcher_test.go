@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+const testSecret = "whsec_test"
+
+func testCommit() gateway.Commit {
+	now := time.Now().UTC()
+
+	return gateway.Commit{
+		TransactionID: "txn-1",
+		Transaction: &gateway.TransactionStatus{
+			TransactionID: "txn-1",
+			Provider:      "stripe",
+			Status:        gateway.StatusCompleted,
+			Amount:        money.New(1000, "USD"),
+			Version:       1,
+		},
+		Events: []gateway.TransactionEvent{{
+			EventID:    "evt-1",
+			Type:       gateway.EventStatusChanged,
+			FromStatus: gateway.StatusPending,
+			ToStatus:   gateway.StatusCompleted,
+			CreatedAt:  now,
+		}},
+		CommittedAt: now,
+	}
+}
+
+func newTestDispatcher(t *testing.T, policy RetryPolicy, handler http.HandlerFunc) *Dispatcher {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	d := NewDispatcher(WithWorkers(1), WithRetryPolicy(policy))
+	t.Cleanup(func() { _ = d.Close(context.Background()) })
+
+	if _, err := d.Register(server.URL, testSecret); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return d
+}
+
+func fastRetries(attempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  attempts,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     10 * time.Millisecond,
+		Multiplier:   2,
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeliveriesAreSigned(t *testing.T) {
+	received := make(chan error, 1)
+
+	d := newTestDispatcher(t, fastRetries(1), func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err == nil {
+			err = Verify(testSecret, r.Header.Get(SignatureHeader), payload, DefaultSignatureTolerance, time.Now())
+		}
+		if err == nil && Verify("whsec_other", r.Header.Get(SignatureHeader), payload, DefaultSignatureTolerance, time.Now()) == nil {
+			err = errors.New("signature verified with the wrong secret")
+		}
+		if err == nil && Verify(testSecret, r.Header.Get(SignatureHeader), append(payload, ' '), DefaultSignatureTolerance, time.Now()) == nil {
+			err = errors.New("signature verified a tampered payload")
+		}
+		if err == nil && r.Header.Get(EventTypeHeader) != string(EventTransactionCompleted) {
+			err = errors.New("missing event type header")
+		}
+		received <- err
+	})
+	d.Start()
+
+	d.Publish(testCommit())
+
+	select {
+	case err := <-received:
+		if err != nil {
+			t.Fatalf("receiver rejected delivery: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery never arrived")
+	}
+}
+
+func TestVerifyRejectsStaleSignatures(t *testing.T) {
+	payload := []byte(`{"id":"evt-1"}`)
+	signedAt := time.Now()
+	header := Sign(testSecret, signedAt, payload)
+
+	if err := Verify(testSecret, header, payload, DefaultSignatureTolerance, signedAt.Add(time.Minute)); err != nil {
+		t.Fatalf("Verify within tolerance: %v", err)
+	}
+	if err := Verify(testSecret, header, payload, DefaultSignatureTolerance, signedAt.Add(time.Hour)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Verify outside tolerance = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestBackoffGrowsUntilMaxDelay(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := policy.backoff(i + 1); got != expected {
+			t.Fatalf("backoff(%d) = %s, want %s", i+1, got, expected)
+		}
+	}
+}
+
+func TestFailingDeliveriesRetryIntoDeadLetters(t *testing.T) {
+	var (
+		hits     atomic.Int32
+		mu       sync.Mutex
+		arrivals []time.Time
+	)
+
+	d := newTestDispatcher(t, fastRetries(3), func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		mu.Lock()
+		arrivals = append(arrivals, time.Now())
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	d.Start()
+
+	d.Publish(testCommit())
+
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters()) == 1 })
+
+	dead := d.DeadLetters()[0]
+	if dead.Attempts != 3 || hits.Load() != 3 {
+		t.Fatalf("dead after %d attempts and %d requests, want 3 of each", dead.Attempts, hits.Load())
+	}
+	if dead.LastStatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("last status = %d, want %d", dead.LastStatusCode, http.StatusServiceUnavailable)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 1; i < len(arrivals); i++ {
+		if gap, want := arrivals[i].Sub(arrivals[i-1]), fastRetries(3).backoff(i); gap < want {
+			t.Fatalf("attempt %d arrived %s after the previous one, want at least %s", i+1, gap, want)
+		}
+	}
+}
+
+func TestRedeliverOnlyRetriesDeadLetters(t *testing.T) {
+	var healthy atomic.Bool
+	var hits atomic.Int32
+
+	d := newTestDispatcher(t, fastRetries(1), func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	d.Start()
+
+	d.Publish(testCommit())
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters()) == 1 })
+
+	dead := d.DeadLetters()[0]
+	healthy.Store(true)
+
+	redelivered, err := d.Redeliver(dead.ID)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if redelivered.State != DeliveryPending || redelivered.Attempts != 0 {
+		t.Fatalf("redelivered = %s after %d attempts, want pending with 0", redelivered.State, redelivered.Attempts)
+	}
+
+	waitFor(t, "redelivery", func() bool {
+		_, err := d.Delivery(dead.ID)
+		return errors.Is(err, ErrDeliveryNotFound)
+	})
+	if hits.Load() != 2 {
+		t.Fatalf("receiver saw %d requests, want 2", hits.Load())
+	}
+
+	if _, err := d.Redeliver(dead.ID); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Fatalf("Redeliver after success = %v, want ErrDeliveryNotFound", err)
+	}
+}
+
+func TestRedeliverRejectsPendingDeliveries(t *testing.T) {
+	d := newTestDispatcher(t, fastRetries(1), func(w http.ResponseWriter, r *http.Request) {})
+
+	d.Publish(testCommit())
+
+	pending := d.Pending()
+	if len(pending) != 1 {
+		t.Fatalf("pending deliveries = %d, want 1", len(pending))
+	}
+
+	if _, err := d.Redeliver(pending[0].ID); !errors.Is(err, ErrDeliveryNotDead) {
+		t.Fatalf("Redeliver(pending) = %v, want ErrDeliveryNotDead", err)
+	}
+}
@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+var (
+	ErrInvalidEndpoint  = errors.New("invalid webhook endpoint")
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead  = errors.New("webhook delivery is not dead-lettered")
+)
+
+type EventType string
+
+const (
+	EventTransactionAuthorized        EventType = "transaction.authorized"
+	EventTransactionCompleted         EventType = "transaction.completed"
+	EventTransactionFailed            EventType = "transaction.failed"
+	EventTransactionVoided            EventType = "transaction.voided"
+	EventTransactionRefunded          EventType = "transaction.refunded"
+	EventTransactionPartiallyRefunded EventType = "transaction.partially_refunded"
+)
+
+var eventTypes = map[gateway.TransactionStatusType]EventType{
+	gateway.StatusAuthorized:        EventTransactionAuthorized,
+	gateway.StatusCompleted:         EventTransactionCompleted,
+	gateway.StatusFailed:            EventTransactionFailed,
+	gateway.StatusVoided:            EventTransactionVoided,
+	gateway.StatusRefund:            EventTransactionRefunded,
+	gateway.StatusPartiallyRefunded: EventTransactionPartiallyRefunded,
+}
+
+func IsKnownEventType(t EventType) bool {
+	for _, known := range eventTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+type Endpoint struct {
+	ID         string
+	URL        string
+	Secret     string
+	EventTypes []EventType
+	CreatedAt  time.Time
+}
+
+func (e *Endpoint) subscribes(t EventType) bool {
+	return len(e.EventTypes) == 0 || slices.Contains(e.EventTypes, t)
+}
+
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+type EventData struct {
+	TransactionID     string                        `json:"transaction_id"`
+	Provider          string                        `json:"provider"`
+	Status            gateway.TransactionStatusType `json:"status"`
+	PreviousStatus    gateway.TransactionStatusType `json:"previous_status,omitempty"`
+	Amount            money.Money                   `json:"amount"`
+	CapturedAmount    money.Money                   `json:"captured_amount"`
+	RefundedAmount    money.Money                   `json:"refunded_amount"`
+	Reason            string                        `json:"reason,omitempty"`
+	MerchantReference string                        `json:"merchant_reference,omitempty"`
+	Metadata          map[string]string             `json:"metadata,omitempty"`
+	Version           int64                         `json:"version"`
+}
+
+type DeliveryState string
+
+const (
+	DeliveryPending   DeliveryState = "pending"
+	DeliveryDelivered DeliveryState = "delivered"
+	DeliveryDead      DeliveryState = "dead"
+)
+
+type Delivery struct {
+	ID             string
+	EndpointID     string
+	Event          Event
+	State          DeliveryState
+	Attempts       int
+	LastError      string
+	LastStatusCode int
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func eventsFromCommit(commit gateway.Commit) []Event {
+	t := commit.Transaction
+	if t == nil {
+		return nil
+	}
+
+	var events []Event
+	for _, e := range commit.Events {
+		if e.Type != gateway.EventStatusChanged {
+			continue
+		}
+
+		eventType, ok := eventTypes[e.ToStatus]
+		if !ok {
+			continue
+		}
+
+		events = append(events, Event{
+			ID:        e.EventID,
+			Type:      eventType,
+			CreatedAt: e.CreatedAt,
+			Data: EventData{
+				TransactionID:     t.TransactionID,
+				Provider:          t.Provider,
+				Status:            e.ToStatus,
+				PreviousStatus:    e.FromStatus,
+				Amount:            t.Amount,
+				CapturedAmount:    t.CapturedAmount,
+				RefundedAmount:    t.RefundedAmount,
+				Reason:            e.Reason,
+				MerchantReference: t.MerchantReference,
+				Metadata:          t.Metadata.Clone(),
+				Version:           t.Version,
+			},
+		})
+	}
+
+	return events
+}
+
+func validateEndpoint(rawURL string, types []EventType) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidEndpoint)
+	}
+
+	for _, t := range types {
+		if !IsKnownEventType(t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidEndpoint, t)
+		}
+	}
+
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func generateID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -4,57 +4,342 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"factory-method/internal/payment/card"
+	"factory-method/internal/payment/factory"
 	"factory-method/internal/payment/gateway"
 	"factory-method/internal/payment/processor"
+	"factory-method/internal/payment/routing"
+	"factory-method/internal/payment/vault"
+	"factory-method/internal/payment/webhook"
+	"factory-method/pkg/money"
 )
 
 type Handler struct {
-	paypalProcessor *processor.Processor
-	stripeProcessor *processor.Processor
+	registry        *factory.Registry
+	vault           *vault.Vault
+	webhooks        *webhook.Dispatcher
+	defaultProvider ProviderType
+	failover        []ProviderType
+	strategy        routing.Strategy
+	processors      map[string]*processor.Processor
+	providers       *processor.RoutingProcessor
+	router          *processor.RoutingProcessor
+	mu              sync.Mutex
 }
 
-func NewHandler(paypalProcessor *processor.Processor, stripeProcessor *processor.Processor) *Handler {
-	return &Handler{
-		paypalProcessor: paypalProcessor,
-		stripeProcessor: stripeProcessor,
+type paymentProcessor interface {
+	MakePayment(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error)
+	Authorize(ctx context.Context, details gateway.PaymentDetails) (*gateway.TransactionStatus, error)
+	Capture(ctx context.Context, details gateway.CaptureDetails) (*gateway.TransactionStatus, error)
+	Void(ctx context.Context, details gateway.VoidDetails) (*gateway.TransactionStatus, error)
+	MakeRefund(ctx context.Context, details gateway.RefundDetails) (*gateway.TransactionStatus, error)
+	CheckStatus(ctx context.Context, transactionID string) (*gateway.TransactionStatus, error)
+	GetHistory(ctx context.Context, transactionID string) ([]gateway.TransactionEvent, error)
+	List(ctx context.Context, query gateway.ListQuery) (*gateway.TransactionPage, error)
+}
+
+type HandlerOption func(*Handler)
+
+func WithDefaultProvider(provider ProviderType) HandlerOption {
+	return func(h *Handler) {
+		h.defaultProvider = provider
+	}
+}
+
+func WithFailover(providers ...ProviderType) HandlerOption {
+	return func(h *Handler) {
+		h.failover = append([]ProviderType(nil), providers...)
+	}
+}
+
+func WithRoutingStrategy(strategy routing.Strategy) HandlerOption {
+	return func(h *Handler) {
+		h.strategy = strategy
+	}
+}
+
+func WithVault(v *vault.Vault) HandlerOption {
+	return func(h *Handler) {
+		h.vault = v
+	}
+}
+
+func WithWebhooks(d *webhook.Dispatcher) HandlerOption {
+	return func(h *Handler) {
+		h.webhooks = d
+	}
+}
+
+func NewHandler(registry *factory.Registry, opts ...HandlerOption) *Handler {
+	h := &Handler{
+		registry:   registry,
+		processors: make(map[string]*processor.Processor),
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 type ProviderType string
 
 const (
-	StripeProvider ProviderType = "stripe"
-	PaypalProvider ProviderType = "paypal"
+	StripeProvider ProviderType = factory.StripeProviderName
+	PaypalProvider ProviderType = factory.PaypalProviderName
 )
 
+type ProviderInfo struct {
+	Name                string        `json:"name"`
+	DisplayName         string        `json:"display_name"`
+	SupportedCurrencies []string      `json:"supported_currencies,omitempty"`
+	Circuit             *CircuitState `json:"circuit,omitempty"`
+}
+
+type CircuitState struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       int64      `json:"total_failures"`
+	Rejected            int64      `json:"rejected"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
+
 type PaymentDetails struct {
-	provider    ProviderType
-	Amount      float64
-	Currency    string
-	CardNumber  string
-	CardHolder  string
-	ExpiryDate  string
-	CVV         string
-	Description string
+	Provider          ProviderType      `json:"provider,omitempty"`
+	IdempotencyKey    string            `json:"idempotency_key,omitempty"`
+	Amount            money.Money       `json:"amount"`
+	CardToken         string            `json:"card_token,omitempty"`
+	CardNumber        string            `json:"card_number,omitempty"`
+	CardHolder        string            `json:"card_holder,omitempty"`
+	ExpiryDate        string            `json:"expiry_date,omitempty"`
+	CVV               string            `json:"cvv"`
+	Description       string            `json:"description,omitempty"`
+	MerchantReference string            `json:"merchant_reference,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+type RouteCandidate struct {
+	Provider     ProviderType `json:"provider"`
+	EstimatedFee money.Money  `json:"estimated_fee"`
+}
+
+type RouteExclusion struct {
+	Provider ProviderType `json:"provider"`
+	Reason   string       `json:"reason"`
+}
+
+type RouteStep struct {
+	Rule    string `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+type RouteExplanation struct {
+	Rule      string           `json:"rule"`
+	Providers []RouteCandidate `json:"providers"`
+	Excluded  []RouteExclusion `json:"excluded,omitempty"`
+	Trace     []RouteStep      `json:"trace"`
+}
+
+type TokenizeDetails struct {
+	CardNumber string `json:"card_number"`
+	CardHolder string `json:"card_holder"`
+	ExpiryDate string `json:"expiry_date"`
+	CVV        string `json:"cvv"`
+}
+
+type CardToken struct {
+	Token      string    `json:"token"`
+	Brand      string    `json:"brand"`
+	Last4      string    `json:"last4"`
+	ExpiryDate string    `json:"expiry_date,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookEndpointDetails struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+type WebhookEndpoint struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	EndpointID     string     `json:"endpoint_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	TransactionID  string     `json:"transaction_id"`
+	State          string     `json:"state"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 type RefundDetails struct {
-	provider      ProviderType
-	TransactionID string
-	Amount        float64
-	Reason        string
+	Provider          ProviderType      `json:"provider,omitempty"`
+	IdempotencyKey    string            `json:"idempotency_key,omitempty"`
+	TransactionID     string            `json:"transaction_id"`
+	Amount            money.Money       `json:"amount"`
+	Reason            string            `json:"reason,omitempty"`
+	MerchantReference string            `json:"merchant_reference,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+type CaptureDetails struct {
+	Provider       ProviderType `json:"provider,omitempty"`
+	IdempotencyKey string       `json:"idempotency_key,omitempty"`
+	TransactionID  string       `json:"transaction_id"`
+	Amount         money.Money  `json:"amount,omitzero"`
+}
+
+type VoidDetails struct {
+	Provider      ProviderType `json:"provider,omitempty"`
+	TransactionID string       `json:"transaction_id"`
+	Reason        string       `json:"reason,omitempty"`
 }
 
 type CheckStatusDetails struct {
-	provider      ProviderType
-	TransactionID string
+	Provider      ProviderType `json:"provider,omitempty"`
+	TransactionID string       `json:"transaction_id"`
+}
+
+type HistoryDetails struct {
+	Provider      ProviderType `json:"provider,omitempty"`
+	TransactionID string       `json:"transaction_id"`
 }
 
+type ListTransactionsDetails struct {
+	Provider          ProviderType            `json:"provider,omitempty"`
+	MerchantReference string                  `json:"merchant_reference,omitempty"`
+	Statuses          []TransactionStatusType `json:"statuses,omitempty"`
+	Currency          string                  `json:"currency,omitempty"`
+	MinAmount         *int64                  `json:"min_amount,omitempty"`
+	MaxAmount         *int64                  `json:"max_amount,omitempty"`
+	CreatedAfter      time.Time               `json:"created_after,omitzero"`
+	CreatedBefore     time.Time               `json:"created_before,omitzero"`
+	SortBy            string                  `json:"sort,omitempty"`
+	Order             string                  `json:"order,omitempty"`
+	Limit             int                     `json:"limit,omitempty"`
+	Cursor            string                  `json:"cursor,omitempty"`
+}
+
+type TransactionList struct {
+	Transactions []*TransactionStatus `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+}
+
+type TransactionEvent struct {
+	EventID    string                `json:"event_id"`
+	Type       EventType             `json:"type"`
+	FromStatus TransactionStatusType `json:"from_status,omitempty"`
+	ToStatus   TransactionStatusType `json:"to_status,omitempty"`
+	Amount     money.Money           `json:"amount,omitzero"`
+	Actor      string                `json:"actor"`
+	Reason     string                `json:"reason,omitempty"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+type EventType string
+
+const (
+	EventCreated         EventType = "created"
+	EventStatusChanged   EventType = "status_changed"
+	EventRefundRequested EventType = "refund_requested"
+	EventError           EventType = "error"
+)
+
+type RefundRecord struct {
+	RefundID          string            `json:"refund_id"`
+	Amount            money.Money       `json:"amount"`
+	Reason            string            `json:"reason,omitempty"`
+	MerchantReference string            `json:"merchant_reference,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+}
+
+const (
+	SchemaV1             = 1
+	SchemaV2             = 2
+	CurrentSchemaVersion = SchemaV2
+)
+
 type TransactionStatus struct {
-	Status   TransactionStatusType
-	Amount   float64
-	Currency string
+	SchemaVersion          int                   `json:"schema_version"`
+	TransactionID          string                `json:"transaction_id"`
+	Provider               ProviderType          `json:"provider,omitempty"`
+	Status                 TransactionStatusType `json:"status"`
+	Amount                 money.Money           `json:"amount"`
+	CapturedAmount         money.Money           `json:"captured_amount"`
+	RefundedAmount         money.Money           `json:"refunded_amount"`
+	RefundableAmount       money.Money           `json:"refundable_amount"`
+	Fee                    money.Money           `json:"fee"`
+	Refunds                []RefundRecord        `json:"refunds,omitempty"`
+	MerchantReference      string                `json:"merchant_reference,omitempty"`
+	Metadata               map[string]string     `json:"metadata,omitempty"`
+	FailureReason          string                `json:"failure_reason,omitempty"`
+	AuthorizationExpiresAt time.Time             `json:"authorization_expires_at,omitzero"`
+	CreatedAt              time.Time             `json:"created_at"`
+	UpdatedAt              time.Time             `json:"updated_at"`
+}
+
+type transactionStatusV1 struct {
+	Status                 TransactionStatusType `json:"status"`
+	Amount                 money.Money           `json:"amount"`
+	CapturedAmount         money.Money           `json:"captured_amount"`
+	RefundedAmount         money.Money           `json:"refunded_amount"`
+	Refunds                []refundRecordV1      `json:"refunds,omitempty"`
+	AuthorizationExpiresAt time.Time             `json:"authorization_expires_at,omitzero"`
+}
+
+type refundRecordV1 struct {
+	RefundID  string      `json:"refund_id"`
+	Amount    money.Money `json:"amount"`
+	Reason    string      `json:"reason,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func (s *TransactionStatus) AtVersion(version int) any {
+	if version == SchemaV1 {
+		var refunds []refundRecordV1
+		for _, r := range s.Refunds {
+			refunds = append(refunds, refundRecordV1{
+				RefundID:  r.RefundID,
+				Amount:    r.Amount,
+				Reason:    r.Reason,
+				CreatedAt: r.CreatedAt,
+			})
+		}
+
+		return transactionStatusV1{
+			Status:                 s.Status,
+			Amount:                 s.Amount,
+			CapturedAmount:         s.CapturedAmount,
+			RefundedAmount:         s.RefundedAmount,
+			Refunds:                refunds,
+			AuthorizationExpiresAt: s.AuthorizationExpiresAt,
+		}
+	}
+
+	versioned := *s
+	versioned.SchemaVersion = version
+
+	return versioned
 }
 
 type TransactionStatusType string
@@ -64,21 +349,90 @@ const (
 	StatusCompleted TransactionStatusType = "completed"
 	StatusFailed    TransactionStatusType = "failed"
 	StatusRefund    TransactionStatusType = "refund"
+
+	StatusPartiallyRefunded TransactionStatusType = "partially_refunded"
+	StatusAuthorized        TransactionStatusType = "authorized"
+	StatusVoided            TransactionStatusType = "voided"
 )
 
 var (
 	ErrInvalidPaymentDetails = errors.New("invalid payment details")
 	ErrInvalidRefundDetails  = errors.New("invalid refund details")
+	ErrInvalidCaptureDetails = errors.New("invalid capture details")
+	ErrInvalidVoidDetails    = errors.New("invalid void details")
+	ErrInvalidTransactionID  = errors.New("invalid transaction ID")
+	ErrInvalidListQuery      = errors.New("invalid transaction list query")
+	ErrInvalidCardDetails    = errors.New("invalid card details")
+	ErrVaultUnavailable      = errors.New("card tokenization is not configured")
+	ErrRoutingUnavailable    = errors.New("payment routing is not configured")
+	ErrWebhooksUnavailable   = errors.New("webhooks are not configured")
+	ErrInvalidWebhook        = errors.New("invalid webhook endpoint")
+	ErrWebhookNotFound       = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound      = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead       = errors.New("webhook delivery is not dead-lettered")
 	ErrUnknownProvider       = errors.New("unknown payment gateway specified")
+	ErrProviderRequired      = errors.New("payment gateway must be specified")
+	ErrIdempotencyConflict   = errors.New("idempotency key reused with different request")
+	ErrPaymentDeclined       = errors.New("payment declined")
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrTransactionConflict   = errors.New("transaction state conflict")
+	ErrProviderUnavailable   = errors.New("payment provider temporarily unavailable")
 	ErrInternal              = errors.New("internal error")
 )
 
+type DeclineError struct {
+	Code    string
+	Message string
+}
+
+func (e *DeclineError) Error() string {
+	return ErrPaymentDeclined.Error() + ": " + e.Message
+}
+
+func (e *DeclineError) Unwrap() error {
+	return ErrPaymentDeclined
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Err    error
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return e.Err.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 func (h *Handler) MakePayment(ctx context.Context, details PaymentDetails) (*TransactionStatus, error) {
 	if err := validatePaymentDetails(details); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidPaymentDetails, err)
+		return nil, err
 	}
 
-	p, err := h.resolveProcessor(details.provider)
+	details, err := h.tokenizePaymentCard(ctx, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if !h.routed(details.Provider) {
+		if err := h.validateProviderCurrency(details.Provider, details.Amount.Currency()); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := h.resolvePaymentProcessor(ctx, details)
 
 	if err != nil {
 		return nil, err
@@ -86,18 +440,89 @@ func (h *Handler) MakePayment(ctx context.Context, details PaymentDetails) (*Tra
 
 	status, err := p.MakePayment(ctx, convertToProcessorPaymentDetails(details))
 	if err != nil {
-		return nil, ErrInternal
+		return nil, convertProcessorError(ErrInvalidPaymentDetails, err)
+	}
+
+	return convertFromProcessorStatus(status), nil
+}
+
+func (h *Handler) Authorize(ctx context.Context, details PaymentDetails) (*TransactionStatus, error) {
+	if err := validatePaymentDetails(details); err != nil {
+		return nil, err
+	}
+
+	details, err := h.tokenizePaymentCard(ctx, details)
+	if err != nil {
+		return nil, err
+	}
+
+	if !h.routed(details.Provider) {
+		if err := h.validateProviderCurrency(details.Provider, details.Amount.Currency()); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := h.resolvePaymentProcessor(ctx, details)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := p.Authorize(ctx, convertToProcessorPaymentDetails(details))
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidPaymentDetails, err)
+	}
+
+	return convertFromProcessorStatus(status), nil
+}
+
+func (h *Handler) Capture(ctx context.Context, details CaptureDetails) (*TransactionStatus, error) {
+	if details.TransactionID == "" || details.Amount.IsNegative() {
+		return nil, fmt.Errorf("%w: transaction ID or amount is invalid", ErrInvalidCaptureDetails)
+	}
+
+	p, err := h.resolveProcessor(details.Provider)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := p.Capture(ctx, convertToProcessorCaptureDetails(details))
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidCaptureDetails, err)
+	}
+
+	return convertFromProcessorStatus(status), nil
+}
+
+func (h *Handler) Void(ctx context.Context, details VoidDetails) (*TransactionStatus, error) {
+	if details.TransactionID == "" {
+		return nil, fmt.Errorf("%w: empty transaction ID provided", ErrInvalidVoidDetails)
+	}
+
+	p, err := h.resolveProcessor(details.Provider)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := p.Void(ctx, gateway.VoidDetails{
+		TransactionID: details.TransactionID,
+		Reason:        details.Reason,
+	})
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidVoidDetails, err)
 	}
 
 	return convertFromProcessorStatus(status), nil
 }
 
 func (h *Handler) MakeRefund(ctx context.Context, details RefundDetails) (*TransactionStatus, error) {
-	if details.TransactionID == "" || details.Amount <= 0 {
+	if details.TransactionID == "" || !details.Amount.IsPositive() || details.Amount.Currency() == "" {
 		return nil, fmt.Errorf("%w: transaction ID or amount is invalid", ErrInvalidRefundDetails)
 	}
 
-	p, err := h.resolveProcessor(details.provider)
+	p, err := h.resolveProcessor(details.Provider)
 
 	if err != nil {
 		return nil, err
@@ -105,7 +530,7 @@ func (h *Handler) MakeRefund(ctx context.Context, details RefundDetails) (*Trans
 
 	status, err := p.MakeRefund(ctx, convertToProcessorRefundDetails(details))
 	if err != nil {
-		return nil, ErrInternal
+		return nil, convertProcessorError(ErrInvalidRefundDetails, err)
 	}
 
 	return convertFromProcessorStatus(status), nil
@@ -113,10 +538,10 @@ func (h *Handler) MakeRefund(ctx context.Context, details RefundDetails) (*Trans
 
 func (h *Handler) CheckStatus(ctx context.Context, details CheckStatusDetails) (*TransactionStatus, error) {
 	if details.TransactionID == "" {
-		return nil, fmt.Errorf("empty transaction ID provided")
+		return nil, fmt.Errorf("%w: empty transaction ID provided", ErrInvalidTransactionID)
 	}
 
-	p, err := h.resolveProcessor(details.provider)
+	p, err := h.resolveProcessor(details.Provider)
 
 	if err != nil {
 		return nil, err
@@ -124,73 +549,807 @@ func (h *Handler) CheckStatus(ctx context.Context, details CheckStatusDetails) (
 
 	status, err := p.CheckStatus(ctx, details.TransactionID)
 	if err != nil {
-		return nil, ErrInternal
+		return nil, convertProcessorError(ErrInvalidTransactionID, err)
 	}
 
 	return convertFromProcessorStatus(status), nil
 }
 
-func (h *Handler) resolveProcessor(provider ProviderType) (*processor.Processor, error) {
-	var p *processor.Processor
+func (h *Handler) GetHistory(ctx context.Context, details HistoryDetails) ([]TransactionEvent, error) {
+	if details.TransactionID == "" {
+		return nil, fmt.Errorf("%w: empty transaction ID provided", ErrInvalidTransactionID)
+	}
 
-	switch provider {
-	case StripeProvider:
-		p = h.stripeProcessor
-	case PaypalProvider:
-		p = h.paypalProcessor
-	default:
-		return nil, fmt.Errorf("%w: %v", ErrUnknownProvider, provider)
+	p, err := h.resolveProcessor(details.Provider)
+
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := p.GetHistory(ctx, details.TransactionID)
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidTransactionID, err)
+	}
+
+	return convertFromProcessorHistory(history), nil
+}
+
+func (h *Handler) ListTransactions(ctx context.Context, details ListTransactionsDetails) (*TransactionList, error) {
+	if err := validateListTransactionsDetails(details); err != nil {
+		return nil, err
+	}
+
+	var (
+		p   paymentProcessor
+		err error
+	)
+
+	if details.Provider == "" && h.defaultProvider == "" {
+		p, err = h.resolveProviderRouter()
+	} else {
+		p, err = h.resolveProcessor(details.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := p.List(ctx, convertToProcessorListQuery(details))
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidListQuery, err)
+	}
+
+	return convertFromProcessorPage(page), nil
+}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return gateway.WithActor(ctx, actor)
+}
+
+func (h *Handler) Tokenize(ctx context.Context, details TokenizeDetails) (*CardToken, error) {
+	if h.vault == nil {
+		return nil, ErrVaultUnavailable
+	}
+
+	c := card.Card{
+		Number: details.CardNumber,
+		Holder: details.CardHolder,
+		Expiry: details.ExpiryDate,
+		CVV:    details.CVV,
+	}
+	if err := validateCard(ErrInvalidCardDetails, c); err != nil {
+		return nil, err
+	}
+
+	token, err := h.vault.Tokenize(ctx, c)
+	if err != nil {
+		return nil, convertVaultError(ErrInvalidCardDetails, err)
+	}
+
+	return convertFromVaultToken(token), nil
+}
+
+func (h *Handler) ExplainRoute(ctx context.Context, details PaymentDetails) (*RouteExplanation, error) {
+	if h.strategy == nil {
+		return nil, ErrRoutingUnavailable
+	}
+
+	if !details.Amount.IsPositive() || details.Amount.Currency() == "" {
+		return nil, fmt.Errorf("%w: amount and currency are required", ErrInvalidPaymentDetails)
+	}
+
+	decision, err := h.decideRoute(ctx, details)
+	if err != nil && !errors.Is(err, routing.ErrNoRoute) {
+		return nil, convertProcessorError(ErrInvalidPaymentDetails, err)
+	}
+
+	return convertFromRoutingDecision(decision), nil
+}
+
+func (h *Handler) RegisterWebhook(ctx context.Context, details WebhookEndpointDetails) (*WebhookEndpoint, error) {
+	if h.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+
+	errs := &gateway.ValidationError{}
+	if details.URL == "" {
+		errs.Add("url", gateway.CodeRequired, "url is required")
+	}
+	types := make([]webhook.EventType, 0, len(details.EventTypes))
+	for _, t := range details.EventTypes {
+		if !webhook.IsKnownEventType(webhook.EventType(t)) {
+			errs.Add("event_types", gateway.CodeUnsupported, fmt.Sprintf("unknown event type %q", t))
+			continue
+		}
+		types = append(types, webhook.EventType(t))
+	}
+	if err := errs.ErrOrNil(); err != nil {
+		return nil, convertValidationError(ErrInvalidWebhook, errs)
+	}
+
+	endpoint, err := h.webhooks.Register(details.URL, details.Secret, types...)
+	if err != nil {
+		return nil, convertWebhookError(err)
+	}
+
+	return convertFromWebhookEndpoint(endpoint, true), nil
+}
+
+func (h *Handler) ListWebhooks(ctx context.Context) ([]WebhookEndpoint, error) {
+	if h.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+
+	endpoints := h.webhooks.Endpoints()
+	result := make([]WebhookEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		result = append(result, *convertFromWebhookEndpoint(endpoint, false))
+	}
+
+	return result, nil
+}
+
+func (h *Handler) DeleteWebhook(ctx context.Context, id string) error {
+	if h.webhooks == nil {
+		return ErrWebhooksUnavailable
+	}
+
+	if err := h.webhooks.Unregister(id); err != nil {
+		return convertWebhookError(err)
+	}
+
+	return nil
+}
+
+func (h *Handler) ListDeadLetters(ctx context.Context) ([]WebhookDelivery, error) {
+	if h.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+
+	deliveries := h.webhooks.DeadLetters()
+	result := make([]WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, *convertFromWebhookDelivery(delivery))
+	}
+
+	return result, nil
+}
+
+func (h *Handler) RedeliverWebhook(ctx context.Context, id string) (*WebhookDelivery, error) {
+	if h.webhooks == nil {
+		return nil, ErrWebhooksUnavailable
+	}
+
+	delivery, err := h.webhooks.Redeliver(id)
+	if err != nil {
+		return nil, convertWebhookError(err)
+	}
+
+	return convertFromWebhookDelivery(delivery), nil
+}
+
+func (h *Handler) tokenizePaymentCard(ctx context.Context, details PaymentDetails) (PaymentDetails, error) {
+	if h.vault == nil || details.CardNumber == "" {
+		return details, nil
+	}
+
+	c := card.Card{
+		Number: details.CardNumber,
+		Holder: details.CardHolder,
+		Expiry: details.ExpiryDate,
+		CVV:    details.CVV,
+	}
+	if err := validateCard(ErrInvalidPaymentDetails, c); err != nil {
+		return details, err
+	}
+
+	token, err := h.vault.Tokenize(ctx, c)
+	if err != nil {
+		return details, convertVaultError(ErrInvalidPaymentDetails, err)
+	}
+
+	details.CardToken = token.ID
+	details.CardNumber = ""
+	details.CardHolder = ""
+	details.ExpiryDate = ""
+
+	return details, nil
+}
+
+func (h *Handler) ListProviders() []ProviderInfo {
+	providers := h.registry.List()
+
+	infos := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		info := ProviderInfo{
+			Name:                p.Name,
+			DisplayName:         p.Metadata.DisplayName,
+			SupportedCurrencies: p.Metadata.SupportedCurrencies,
+		}
+
+		if reporter, ok := p.Factory.(factory.CircuitBreakerReporter); ok {
+			info.Circuit = convertFromBreakerStats(reporter.CircuitBreakerStats())
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+func (h *Handler) ValidProviders() []ProviderType {
+	providers := h.registry.List()
+
+	names := make([]ProviderType, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, ProviderType(p.Name))
+	}
+
+	return names
+}
+
+func (h *Handler) resolveProvider(provider ProviderType) (factory.Provider, error) {
+	if provider == "" {
+		provider = h.defaultProvider
+	}
+
+	if provider == "" {
+		providers := h.registry.List()
+		if len(providers) == 1 {
+			return providers[0], nil
+		}
+
+		return factory.Provider{}, fmt.Errorf("%w (valid providers: %s)", ErrProviderRequired, h.validProviderNames())
+	}
+
+	registered, err := h.registry.Lookup(string(provider))
+	if err != nil {
+		return factory.Provider{}, fmt.Errorf("%w: %q (valid providers: %s)", ErrUnknownProvider, provider, h.validProviderNames())
+	}
+
+	return registered, nil
+}
+
+func (h *Handler) resolveProcessor(provider ProviderType) (paymentProcessor, error) {
+	if h.routed(provider) {
+		return h.resolveRouter()
+	}
+
+	registered, err := h.resolveProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.processorLocked(registered)
+}
+
+func (h *Handler) resolvePaymentProcessor(ctx context.Context, details PaymentDetails) (paymentProcessor, error) {
+	if !h.routed(details.Provider) || h.strategy == nil {
+		return h.resolveProcessor(details.Provider)
+	}
+
+	router, err := h.resolveProviderRouter()
+	if err != nil {
+		return nil, err
+	}
+
+	decision, err := h.decideRoute(ctx, details)
+	if err != nil {
+		return nil, convertProcessorError(ErrInvalidPaymentDetails, err)
+	}
+
+	p, err := router.Via(decision.Providers()...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRoutingUnavailable, err)
 	}
 
 	return p, nil
 }
 
-func validatePaymentDetails(details PaymentDetails) error {
-	if details.Amount <= 0 {
-		return errors.New("amount must be greater than zero")
+func (h *Handler) decideRoute(ctx context.Context, details PaymentDetails) (routing.Decision, error) {
+	request := routing.Request{
+		Amount: details.Amount,
+		Brand:  card.Unknown,
 	}
-	if details.Currency == "" {
-		return errors.New("currency is required")
+
+	switch {
+	case details.CardNumber != "":
+		request.Brand = card.DetectBrand(details.CardNumber)
+	case details.CardToken != "" && h.vault != nil:
+		if token, err := h.vault.Lookup(ctx, details.CardToken); err == nil {
+			request.Brand = token.Brand
+		}
+	}
+
+	return h.strategy.Route(ctx, request)
+}
+
+func (h *Handler) resolveRouter() (*processor.RoutingProcessor, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.initRoutersLocked(); err != nil {
+		return nil, err
 	}
-	if details.CardNumber == "" {
-		return errors.New("card number is required")
+
+	return h.router, nil
+}
+
+func (h *Handler) resolveProviderRouter() (*processor.RoutingProcessor, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.initRoutersLocked(); err != nil {
+		return nil, err
 	}
-	if details.CardHolder == "" {
-		return errors.New("card holder name is required")
+
+	return h.providers, nil
+}
+
+func (h *Handler) initRoutersLocked() error {
+	if h.router != nil {
+		return nil
 	}
-	if details.ExpiryDate == "" {
-		return errors.New("expiry date is required")
+
+	registered := h.registry.List()
+	routes := make([]processor.Route, 0, len(registered))
+	for _, provider := range registered {
+		p, err := h.processorLocked(provider)
+		if err != nil {
+			return err
+		}
+
+		routes = append(routes, processor.Route{
+			Name:      provider.Name,
+			Metadata:  provider.Metadata,
+			Processor: p,
+		})
+	}
+
+	providers := processor.NewRoutingProcessor(routes)
+	router := providers
+
+	if len(h.failover) > 0 {
+		names := make([]string, 0, len(h.failover))
+		for _, provider := range h.failover {
+			registered, err := h.resolveProvider(provider)
+			if err != nil {
+				return err
+			}
+			names = append(names, registered.Name)
+		}
+
+		failover, err := providers.Via(names...)
+		if err != nil {
+			return ErrInternal
+		}
+		router = failover
+	}
+
+	h.providers = providers
+	h.router = router
+
+	return nil
+}
+
+func (h *Handler) processorLocked(registered factory.Provider) (*processor.Processor, error) {
+	if p, ok := h.processors[registered.Name]; ok {
+		return p, nil
+	}
+
+	p, err := processor.NewProcessor(registered.Factory)
+	if err != nil {
+		return nil, ErrInternal
+	}
+
+	h.processors[registered.Name] = p
+
+	return p, nil
+}
+
+func (h *Handler) routed(provider ProviderType) bool {
+	return provider == "" && (len(h.failover) > 0 || h.strategy != nil)
+}
+
+func (h *Handler) validateProviderCurrency(provider ProviderType, currency string) error {
+	registered, err := h.resolveProvider(provider)
+	if err != nil {
+		return err
+	}
+
+	if !registered.Metadata.SupportsCurrency(currency) {
+		return fmt.Errorf("%w: provider %s does not support currency %s", ErrInvalidPaymentDetails, registered.Name, currency)
+	}
+
+	return nil
+}
+
+func (h *Handler) validProviderNames() string {
+	names := h.ValidProviders()
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, string(name))
+	}
+
+	if len(parts) == 0 {
+		return "none registered"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func validatePaymentDetails(details PaymentDetails) error {
+	errs := &gateway.ValidationError{}
+
+	if !details.Amount.IsPositive() {
+		errs.Add("amount", gateway.CodeInvalid, "amount must be greater than zero")
+	}
+	if details.Amount.Currency() == "" {
+		errs.Add("currency", gateway.CodeRequired, "currency is required")
+	}
+	switch {
+	case details.CardNumber != "" && details.CardToken != "":
+		errs.Add("card_token", gateway.CodeInvalid, "card token and card number are mutually exclusive")
+	case details.CardNumber != "":
+		if details.CardHolder == "" {
+			errs.Add("card_holder", gateway.CodeRequired, "card holder name is required")
+		}
+		if details.ExpiryDate == "" {
+			errs.Add("expiry_date", gateway.CodeRequired, "expiry date is required")
+		}
+	case !vault.IsToken(details.CardToken):
+		errs.Add("card_number", gateway.CodeRequired, "card number or card token is required")
 	}
 	if details.CVV == "" {
-		return errors.New("CVV is required")
+		errs.Add("cvv", gateway.CodeRequired, "CVV is required")
 	}
+
+	if err := errs.ErrOrNil(); err != nil {
+		return convertValidationError(ErrInvalidPaymentDetails, errs)
+	}
+
 	return nil
 }
 
+func validateCard(kind error, c card.Card) error {
+	err := card.NewValidator().Validate(c)
+
+	var errs *gateway.ValidationError
+	if errors.As(err, &errs) {
+		return convertValidationError(kind, errs)
+	}
+
+	return err
+}
+
+func validateListTransactionsDetails(details ListTransactionsDetails) error {
+	errs := &gateway.ValidationError{}
+
+	for _, status := range details.Statuses {
+		switch status {
+		case StatusPending, StatusCompleted, StatusFailed, StatusRefund,
+			StatusPartiallyRefunded, StatusAuthorized, StatusVoided:
+		default:
+			errs.Add("status", gateway.CodeUnsupported, fmt.Sprintf("unknown transaction status %q", status))
+		}
+	}
+	if details.Currency != "" && !money.IsKnownCurrency(details.Currency) {
+		errs.Add("currency", gateway.CodeUnsupported, fmt.Sprintf("unknown currency %q", details.Currency))
+	}
+	if details.MinAmount != nil && *details.MinAmount < 0 {
+		errs.Add("min_amount", gateway.CodeInvalid, "min_amount must not be negative")
+	}
+	if details.MaxAmount != nil && *details.MaxAmount < 0 {
+		errs.Add("max_amount", gateway.CodeInvalid, "max_amount must not be negative")
+	}
+
+	if err := errs.ErrOrNil(); err != nil {
+		return convertValidationError(ErrInvalidListQuery, errs)
+	}
+
+	return nil
+}
+
+func convertVaultError(kind error, err error) error {
+	switch {
+	case errors.Is(err, vault.ErrInvalidCard):
+		errs := &gateway.ValidationError{}
+		errs.Add("card_number", gateway.CodeInvalidFormat, "card number must contain only digits")
+		return convertValidationError(kind, errs)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	default:
+		return ErrInternal
+	}
+}
+
+func convertWebhookError(err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidEndpoint):
+		errs := &gateway.ValidationError{}
+		errs.Add("url", gateway.CodeInvalid, "url must be an absolute http or https URL")
+		return convertValidationError(ErrInvalidWebhook, errs)
+	case errors.Is(err, webhook.ErrEndpointNotFound):
+		return ErrWebhookNotFound
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		return ErrDeliveryNotFound
+	case errors.Is(err, webhook.ErrDeliveryNotDead):
+		return ErrDeliveryNotDead
+	default:
+		return ErrInternal
+	}
+}
+
+func convertFromWebhookEndpoint(endpoint webhook.Endpoint, withSecret bool) *WebhookEndpoint {
+	result := &WebhookEndpoint{
+		ID:        endpoint.ID,
+		URL:       endpoint.URL,
+		CreatedAt: endpoint.CreatedAt,
+	}
+
+	if withSecret {
+		result.Secret = endpoint.Secret
+	}
+
+	for _, t := range endpoint.EventTypes {
+		result.EventTypes = append(result.EventTypes, string(t))
+	}
+
+	return result
+}
+
+func convertFromWebhookDelivery(delivery webhook.Delivery) *WebhookDelivery {
+	result := &WebhookDelivery{
+		ID:             delivery.ID,
+		EndpointID:     delivery.EndpointID,
+		EventID:        delivery.Event.ID,
+		EventType:      string(delivery.Event.Type),
+		TransactionID:  delivery.Event.Data.TransactionID,
+		State:          string(delivery.State),
+		Attempts:       delivery.Attempts,
+		LastError:      delivery.LastError,
+		LastStatusCode: delivery.LastStatusCode,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+
+	if delivery.State == webhook.DeliveryPending {
+		next := delivery.NextAttemptAt
+		result.NextAttemptAt = &next
+	}
+
+	return result
+}
+
+func convertFromVaultToken(token vault.Token) *CardToken {
+	return &CardToken{
+		Token:      token.ID,
+		Brand:      string(token.Brand),
+		Last4:      token.Last4,
+		ExpiryDate: token.ExpiryDate,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func convertValidationError(kind error, errs *gateway.ValidationError) *ValidationError {
+	fields := make([]FieldError, 0, len(errs.Fields))
+	for _, field := range errs.Fields {
+		fields = append(fields, FieldError{
+			Field:   field.Field,
+			Code:    field.Code,
+			Message: field.Message,
+		})
+	}
+
+	return &ValidationError{Err: kind, Fields: fields}
+}
+
 func convertToProcessorPaymentDetails(details PaymentDetails) gateway.PaymentDetails {
 	return gateway.PaymentDetails{
-		Amount:      details.Amount,
-		Currency:    details.Currency,
-		CardNumber:  details.CardNumber,
-		CardHolder:  details.CardHolder,
-		ExpiryDate:  details.ExpiryDate,
-		CVV:         details.CVV,
-		Description: details.Description,
+		IdempotencyKey:    details.IdempotencyKey,
+		Amount:            details.Amount,
+		CardToken:         details.CardToken,
+		CardNumber:        details.CardNumber,
+		CardHolder:        details.CardHolder,
+		ExpiryDate:        details.ExpiryDate,
+		CVV:               details.CVV,
+		Description:       details.Description,
+		MerchantReference: details.MerchantReference,
+		Metadata:          details.Metadata,
 	}
 }
 
 func convertToProcessorRefundDetails(details RefundDetails) gateway.RefundDetails {
 	return gateway.RefundDetails{
-		TransactionID: details.TransactionID,
-		Amount:        details.Amount,
-		Reason:        details.Reason,
+		IdempotencyKey:    details.IdempotencyKey,
+		TransactionID:     details.TransactionID,
+		Amount:            details.Amount,
+		Reason:            details.Reason,
+		MerchantReference: details.MerchantReference,
+		Metadata:          details.Metadata,
+	}
+}
+
+func convertToProcessorCaptureDetails(details CaptureDetails) gateway.CaptureDetails {
+	return gateway.CaptureDetails{
+		IdempotencyKey: details.IdempotencyKey,
+		TransactionID:  details.TransactionID,
+		Amount:         details.Amount,
+	}
+}
+
+func convertToProcessorListQuery(details ListTransactionsDetails) gateway.ListQuery {
+	statuses := make([]gateway.TransactionStatusType, 0, len(details.Statuses))
+	for _, status := range details.Statuses {
+		statuses = append(statuses, gateway.TransactionStatusType(status))
+	}
+
+	return gateway.ListQuery{
+		Filter: gateway.ListFilter{
+			Statuses:          statuses,
+			Currency:          strings.ToUpper(details.Currency),
+			MinAmount:         details.MinAmount,
+			MaxAmount:         details.MaxAmount,
+			CreatedAfter:      details.CreatedAfter,
+			CreatedBefore:     details.CreatedBefore,
+			Provider:          string(details.Provider),
+			MerchantReference: details.MerchantReference,
+		},
+		SortBy: gateway.SortField(details.SortBy),
+		Order:  gateway.SortOrder(details.Order),
+		Limit:  details.Limit,
+		Cursor: details.Cursor,
+	}
+}
+
+func convertFromProcessorPage(page *gateway.TransactionPage) *TransactionList {
+	list := &TransactionList{
+		Transactions: make([]*TransactionStatus, 0, len(page.Transactions)),
+		NextCursor:   page.NextCursor,
+	}
+
+	for _, status := range page.Transactions {
+		list.Transactions = append(list.Transactions, convertFromProcessorStatus(status))
 	}
+
+	return list
 }
 
 func convertFromProcessorStatus(status *gateway.TransactionStatus) *TransactionStatus {
+	refunds := make([]RefundRecord, 0, len(status.Refunds))
+	for _, r := range status.Refunds {
+		refunds = append(refunds, RefundRecord{
+			RefundID:          r.RefundID,
+			Amount:            r.Amount,
+			Reason:            r.Reason,
+			MerchantReference: r.MerchantReference,
+			Metadata:          r.Metadata,
+			CreatedAt:         r.CreatedAt,
+		})
+	}
+
+	refundable, err := status.RefundableAmount()
+	if err != nil || !status.IsRefundable() {
+		refundable = money.Zero(status.Amount.Currency())
+	}
+
 	return &TransactionStatus{
-		Status:   TransactionStatusType(status.Status),
-		Amount:   status.Amount,
-		Currency: status.Currency,
+		SchemaVersion:          CurrentSchemaVersion,
+		TransactionID:          status.TransactionID,
+		Provider:               ProviderType(status.Provider),
+		Status:                 TransactionStatusType(status.Status),
+		Amount:                 status.Amount,
+		CapturedAmount:         status.CapturedAmount,
+		RefundedAmount:         status.RefundedAmount,
+		RefundableAmount:       refundable,
+		Fee:                    status.Fee,
+		Refunds:                refunds,
+		MerchantReference:      status.MerchantReference,
+		Metadata:               status.Metadata,
+		FailureReason:          status.ErrorMessage,
+		AuthorizationExpiresAt: status.AuthorizationExpiresAt,
+		CreatedAt:              status.CreatedAt,
+		UpdatedAt:              status.UpdatedAt,
+	}
+}
+
+func convertProcessorError(kind error, err error) error {
+	var validationErr *gateway.ValidationError
+	if errors.As(err, &validationErr) {
+		return convertValidationError(kind, validationErr)
+	}
+	if errors.Is(err, gateway.ErrIdempotencyKeyReused) || errors.Is(err, gateway.ErrIdempotencyKeyInProgress) {
+		return ErrIdempotencyConflict
+	}
+
+	var gatewayErr *gateway.Error
+	if !errors.As(err, &gatewayErr) {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return ErrInternal
+	}
+
+	switch gatewayErr.Kind {
+	case gateway.KindDeclined:
+		return &DeclineError{Code: gatewayErr.DeclineCode, Message: gatewayErr.Message}
+	case gateway.KindInvalidRequest:
+		return fmt.Errorf("%w: %s", kind, gatewayErr.Message)
+	case gateway.KindNotFound:
+		return ErrTransactionNotFound
+	case gateway.KindConflict:
+		return fmt.Errorf("%w: %s", ErrTransactionConflict, gatewayErr.Message)
+	case gateway.KindTransient:
+		return ErrProviderUnavailable
+	default:
+		return ErrInternal
+	}
+}
+
+func convertFromRoutingDecision(decision routing.Decision) *RouteExplanation {
+	explanation := &RouteExplanation{
+		Rule:      decision.Rule,
+		Providers: make([]RouteCandidate, 0, len(decision.Candidates)),
+		Trace:     make([]RouteStep, 0, len(decision.Trace)),
+	}
+
+	for _, c := range decision.Candidates {
+		explanation.Providers = append(explanation.Providers, RouteCandidate{
+			Provider:     ProviderType(c.Provider),
+			EstimatedFee: c.EstimatedFee,
+		})
+	}
+
+	for _, e := range decision.Excluded {
+		explanation.Excluded = append(explanation.Excluded, RouteExclusion{
+			Provider: ProviderType(e.Provider),
+			Reason:   e.Reason,
+		})
+	}
+
+	for _, step := range decision.Trace {
+		explanation.Trace = append(explanation.Trace, RouteStep{
+			Rule:    step.Rule,
+			Matched: step.Matched,
+			Reason:  step.Reason,
+		})
+	}
+
+	return explanation
+}
+
+func convertFromBreakerStats(stats gateway.BreakerStats) *CircuitState {
+	state := &CircuitState{
+		State:               string(stats.State),
+		ConsecutiveFailures: stats.ConsecutiveFailures,
+		TotalFailures:       stats.TotalFailures,
+		Rejected:            stats.Rejected,
+	}
+
+	if !stats.OpenedAt.IsZero() {
+		state.OpenedAt = &stats.OpenedAt
+		state.RetryAt = &stats.RetryAt
+	}
+
+	return state
+}
+
+func convertFromProcessorHistory(history []gateway.TransactionEvent) []TransactionEvent {
+	events := make([]TransactionEvent, 0, len(history))
+	for _, e := range history {
+		events = append(events, TransactionEvent{
+			EventID:    e.EventID,
+			Type:       EventType(e.Type),
+			FromStatus: TransactionStatusType(e.FromStatus),
+			ToStatus:   TransactionStatusType(e.ToStatus),
+			Amount:     e.Amount,
+			Actor:      e.Actor,
+			Reason:     e.Reason,
+			CreatedAt:  e.CreatedAt,
+		})
 	}
+	return events
 }
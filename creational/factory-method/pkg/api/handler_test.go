@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"factory-method/internal/payment/factory"
+	"factory-method/internal/payment/routing"
+	"factory-method/internal/payment/vault"
+	"factory-method/pkg/money"
+)
+
+func newTestRegistry() *factory.Registry {
+	return factory.NewDefaultRegistry(
+		factory.WithoutFaults(),
+		factory.WithoutRetries(),
+		factory.WithoutCircuitBreaker(),
+	)
+}
+
+func TestRuleRoutingReachesProvidersOutsideFailover(t *testing.T) {
+	registry := newTestRegistry()
+
+	strategy, err := routing.NewRuleEngine(routing.RuleSet{
+		Rules: []routing.Rule{{
+			Name:      "eur-to-paypal",
+			When:      routing.Condition{Currencies: []string{"EUR"}},
+			Providers: []string{factory.PaypalProviderName},
+		}},
+		Default: []string{factory.StripeProviderName},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewRuleEngine: %v", err)
+	}
+
+	h := NewHandler(registry,
+		WithFailover(StripeProvider),
+		WithRoutingStrategy(strategy),
+	)
+
+	status, err := h.MakePayment(context.Background(), PaymentDetails{
+		Amount:     money.New(1500, "EUR"),
+		CardNumber: "4111111111111111",
+		CardHolder: "Jane Doe",
+		ExpiryDate: "12/40",
+		CVV:        "123",
+	})
+	if err != nil {
+		t.Fatalf("MakePayment: %v", err)
+	}
+	if status.Provider != PaypalProvider {
+		t.Fatalf("provider = %q, want %q", status.Provider, PaypalProvider)
+	}
+
+	checked, err := h.CheckStatus(context.Background(), CheckStatusDetails{TransactionID: status.TransactionID})
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if checked.Provider != PaypalProvider {
+		t.Fatalf("CheckStatus provider = %q, want %q", checked.Provider, PaypalProvider)
+	}
+}
+
+type countingStorage struct {
+	*vault.MemoryStorage
+	puts int
+}
+
+func (s *countingStorage) Put(ctx context.Context, record vault.Record) error {
+	s.puts++
+	return s.MemoryStorage.Put(ctx, record)
+}
+
+func TestTokenizeValidatesCardBeforeStoring(t *testing.T) {
+	key, err := vault.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	storage := &countingStorage{MemoryStorage: vault.NewMemoryStorage()}
+	v, err := vault.New(key, vault.WithStorage(storage))
+	if err != nil {
+		t.Fatalf("vault.New: %v", err)
+	}
+
+	h := NewHandler(newTestRegistry(), WithVault(v))
+
+	valid := TokenizeDetails{
+		CardNumber: "4242424242424242",
+		CardHolder: "Jane Doe",
+		ExpiryDate: "12/40",
+		CVV:        "123",
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		edit  func(*TokenizeDetails)
+	}{
+		{name: "luhn", field: "card_number", edit: func(d *TokenizeDetails) { d.CardNumber = "4242424242424241" }},
+		{name: "expired", field: "expiry_date", edit: func(d *TokenizeDetails) { d.ExpiryDate = "01/20" }},
+		{name: "cvv", field: "cvv", edit: func(d *TokenizeDetails) { d.CVV = "12" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := valid
+			tt.edit(&details)
+
+			_, err := h.Tokenize(context.Background(), details)
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) || !errors.Is(err, ErrInvalidCardDetails) {
+				t.Fatalf("Tokenize = %v, want ErrInvalidCardDetails", err)
+			}
+			if len(verr.Fields) != 1 || verr.Fields[0].Field != tt.field {
+				t.Fatalf("fields = %+v, want single %s error", verr.Fields, tt.field)
+			}
+		})
+	}
+
+	if storage.puts != 0 {
+		t.Fatalf("vault stored %d tokens for rejected cards, want 0", storage.puts)
+	}
+
+	if _, err := h.Tokenize(context.Background(), valid); err != nil {
+		t.Fatalf("Tokenize(valid): %v", err)
+	}
+}
+
+func TestListTransactionsMergesAllProviders(t *testing.T) {
+	h := NewHandler(newTestRegistry(), WithFailover(StripeProvider))
+	ctx := context.Background()
+
+	payments := []PaymentDetails{
+		{Provider: StripeProvider, Amount: money.New(1000, "USD"), CardNumber: "4242424242424242", MerchantReference: "order-1"},
+		{Provider: PaypalProvider, Amount: money.New(2500, "USD"), CardNumber: "4111111111111111", MerchantReference: "order-2"},
+		{Provider: StripeProvider, Amount: money.New(500, "EUR"), CardNumber: "5555555555554444", MerchantReference: "order-3"},
+		{Provider: PaypalProvider, Amount: money.New(1500, "EUR"), CardNumber: "5105105105105100", MerchantReference: "order-4"},
+	}
+
+	ids := make(map[string]string, len(payments))
+	for _, details := range payments {
+		details.CardHolder = "Jane Doe"
+		details.ExpiryDate = "12/40"
+		details.CVV = "123"
+
+		status, err := h.MakePayment(ctx, details)
+		if err != nil {
+			t.Fatalf("MakePayment(%s): %v", details.MerchantReference, err)
+		}
+		ids[status.TransactionID] = details.MerchantReference
+	}
+
+	minAmount := int64(1000)
+
+	tests := []struct {
+		name    string
+		details ListTransactionsDetails
+		want    []string
+	}{
+		{name: "amount ascending", details: ListTransactionsDetails{SortBy: "amount", Order: "asc"}, want: []string{"order-3", "order-1", "order-4", "order-2"}},
+		{name: "amount descending", details: ListTransactionsDetails{SortBy: "amount"}, want: []string{"order-2", "order-4", "order-1", "order-3"}},
+		{name: "currency", details: ListTransactionsDetails{SortBy: "amount", Currency: "eur"}, want: []string{"order-4", "order-3"}},
+		{name: "minimum amount", details: ListTransactionsDetails{SortBy: "amount", MinAmount: &minAmount}, want: []string{"order-2", "order-4", "order-1"}},
+		{name: "provider", details: ListTransactionsDetails{SortBy: "amount", Provider: PaypalProvider}, want: []string{"order-2", "order-4"}},
+		{name: "merchant reference outside failover", details: ListTransactionsDetails{MerchantReference: "order-4"}, want: []string{"order-4"}},
+		{name: "status", details: ListTransactionsDetails{Statuses: []TransactionStatusType{StatusRefund}}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := h.ListTransactions(ctx, tt.details)
+			if err != nil {
+				t.Fatalf("ListTransactions: %v", err)
+			}
+
+			got := make([]string, 0, len(list.Transactions))
+			for _, status := range list.Transactions {
+				got = append(got, ids[status.TransactionID])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("listed %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var (
+		paged  []string
+		cursor string
+	)
+	for range len(payments) {
+		list, err := h.ListTransactions(ctx, ListTransactionsDetails{SortBy: "amount", Order: "asc", Limit: 1, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListTransactions(cursor %q): %v", cursor, err)
+		}
+		for _, status := range list.Transactions {
+			paged = append(paged, ids[status.TransactionID])
+		}
+		if cursor = list.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if want := []string{"order-3", "order-1", "order-4", "order-2"}; !slices.Equal(paged, want) {
+		t.Fatalf("paged through %v, want %v", paged, want)
+	}
+}
+
+func TestExplainRouteReportsTraceAndExclusions(t *testing.T) {
+	registry := newTestRegistry()
+
+	if _, err := NewHandler(registry).ExplainRoute(context.Background(), PaymentDetails{Amount: money.New(500, "USD")}); !errors.Is(err, ErrRoutingUnavailable) {
+		t.Fatalf("ExplainRoute without strategy = %v, want ErrRoutingUnavailable", err)
+	}
+
+	strategy, err := routing.NewRuleEngine(routing.RuleSet{
+		Rules: []routing.Rule{{
+			Name:      "eur-to-paypal",
+			When:      routing.Condition{Currencies: []string{"EUR"}},
+			Providers: []string{factory.PaypalProviderName},
+		}},
+		Default: []string{factory.PaypalProviderName, factory.StripeProviderName},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewRuleEngine: %v", err)
+	}
+
+	h := NewHandler(registry, WithRoutingStrategy(strategy))
+
+	explanation, err := h.ExplainRoute(context.Background(), PaymentDetails{Amount: money.New(500, "BRL"), CardNumber: "4242424242424242"})
+	if err != nil {
+		t.Fatalf("ExplainRoute: %v", err)
+	}
+
+	if explanation.Rule != "default" || len(explanation.Trace) != 1 || explanation.Trace[0].Matched {
+		t.Fatalf("explanation = %+v, want default after unmatched eur-to-paypal", explanation)
+	}
+	if len(explanation.Providers) != 1 || explanation.Providers[0].Provider != StripeProvider {
+		t.Fatalf("providers = %+v, want stripe", explanation.Providers)
+	}
+	if len(explanation.Excluded) != 1 || explanation.Excluded[0].Provider != PaypalProvider {
+		t.Fatalf("excluded = %+v, want paypal", explanation.Excluded)
+	}
+
+	if _, err := h.ExplainRoute(context.Background(), PaymentDetails{Amount: money.Zero("USD")}); !errors.Is(err, ErrInvalidPaymentDetails) {
+		t.Fatalf("ExplainRoute(zero amount) = %v, want ErrInvalidPaymentDetails", err)
+	}
+}
@@ -0,0 +1,526 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+)
+
+const (
+	actorHeader          = "X-Actor"
+	providerHeader       = "X-Payment-Provider"
+	idempotencyKeyHeader = "Idempotency-Key"
+	schemaVersionHeader  = "X-Schema-Version"
+	maxRequestBodyBytes  = 1 << 20
+)
+
+var (
+	ErrMalformedRequest         = errors.New("malformed request body")
+	ErrUnsupportedSchemaVersion = errors.New("unsupported response schema version")
+)
+
+type errorResponse struct {
+	Error       string       `json:"error"`
+	DeclineCode string       `json:"decline_code,omitempty"`
+	Fields      []FieldError `json:"fields,omitempty"`
+}
+
+func NewHTTPHandler(h *Handler) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/providers", h.handleListProviders)
+	mux.HandleFunc("POST /v1/tokens", h.handleTokenize)
+	mux.HandleFunc("POST /v1/routes/explain", h.handleExplainRoute)
+	mux.HandleFunc("POST /v1/webhooks", h.handleRegisterWebhook)
+	mux.HandleFunc("GET /v1/webhooks", h.handleListWebhooks)
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", h.handleDeleteWebhook)
+	mux.HandleFunc("GET /v1/webhooks/dead-letters", h.handleListDeadLetters)
+	mux.HandleFunc("POST /v1/webhooks/deliveries/{id}/redeliver", h.handleRedeliverWebhook)
+
+	for _, prefix := range []string{"/v1", "/v1/providers/{provider}"} {
+		mux.HandleFunc("POST "+prefix+"/payments", h.handleMakePayment)
+		mux.HandleFunc("POST "+prefix+"/authorizations", h.handleAuthorize)
+		mux.HandleFunc("GET "+prefix+"/transactions", h.handleListTransactions)
+		mux.HandleFunc("GET "+prefix+"/transactions/{id}", h.handleCheckStatus)
+		mux.HandleFunc("GET "+prefix+"/transactions/{id}/history", h.handleGetHistory)
+		mux.HandleFunc("POST "+prefix+"/transactions/{id}/capture", h.handleCapture)
+		mux.HandleFunc("POST "+prefix+"/transactions/{id}/void", h.handleVoid)
+		mux.HandleFunc("POST "+prefix+"/transactions/{id}/refunds", h.handleMakeRefund)
+	}
+
+	return withSchemaVersion(mux)
+}
+
+func withSchemaVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := schemaVersion(r); err != nil {
+			writeError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func schemaVersion(r *http.Request) (int, error) {
+	value := r.Header.Get(schemaVersionHeader)
+	if value == "" {
+		return CurrentSchemaVersion, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < SchemaV1 || version > CurrentSchemaVersion {
+		return 0, fmt.Errorf("%w: %q (supported: %d-%d)", ErrUnsupportedSchemaVersion, value, SchemaV1, CurrentSchemaVersion)
+	}
+
+	return version, nil
+}
+
+func (h *Handler) handleListProviders(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.ListProviders())
+}
+
+func (h *Handler) handleTokenize(w http.ResponseWriter, r *http.Request) {
+	var details TokenizeDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	token, err := h.Tokenize(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, token)
+}
+
+func (h *Handler) handleExplainRoute(w http.ResponseWriter, r *http.Request) {
+	var details PaymentDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	explanation, err := h.ExplainRoute(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, explanation)
+}
+
+func (h *Handler) handleRegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	var details WebhookEndpointDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	endpoint, err := h.RegisterWebhook(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, endpoint)
+}
+
+func (h *Handler) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.ListWebhooks(requestContext(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, endpoints)
+}
+
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.DeleteWebhook(requestContext(r), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.ListDeadLetters(requestContext(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) handleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.RedeliverWebhook(requestContext(r), r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func (h *Handler) handleMakePayment(w http.ResponseWriter, r *http.Request) {
+	var details PaymentDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	details.Provider = providerFromRequest(r, details.Provider)
+	details.IdempotencyKey = idempotencyKey(r, details.IdempotencyKey)
+
+	status, err := h.MakePayment(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusCreated, status)
+}
+
+func (h *Handler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	var details PaymentDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	details.Provider = providerFromRequest(r, details.Provider)
+	details.IdempotencyKey = idempotencyKey(r, details.IdempotencyKey)
+
+	status, err := h.Authorize(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusCreated, status)
+}
+
+func (h *Handler) handleCapture(w http.ResponseWriter, r *http.Request) {
+	var details CaptureDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	details.Provider = providerFromRequest(r, details.Provider)
+	details.TransactionID = r.PathValue("id")
+	details.IdempotencyKey = idempotencyKey(r, details.IdempotencyKey)
+
+	status, err := h.Capture(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusOK, status)
+}
+
+func (h *Handler) handleVoid(w http.ResponseWriter, r *http.Request) {
+	var details VoidDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	details.Provider = providerFromRequest(r, details.Provider)
+	details.TransactionID = r.PathValue("id")
+
+	status, err := h.Void(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusOK, status)
+}
+
+func (h *Handler) handleMakeRefund(w http.ResponseWriter, r *http.Request) {
+	var details RefundDetails
+	if err := decodeRequest(w, r, &details); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	details.Provider = providerFromRequest(r, details.Provider)
+	details.TransactionID = r.PathValue("id")
+	details.IdempotencyKey = idempotencyKey(r, details.IdempotencyKey)
+
+	status, err := h.MakeRefund(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusCreated, status)
+}
+
+func (h *Handler) handleCheckStatus(w http.ResponseWriter, r *http.Request) {
+	details := CheckStatusDetails{
+		Provider:      providerFromRequest(r, ""),
+		TransactionID: r.PathValue("id"),
+	}
+
+	status, err := h.CheckStatus(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransaction(w, r, http.StatusOK, status)
+}
+
+func (h *Handler) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	details := HistoryDetails{
+		Provider:      providerFromRequest(r, ""),
+		TransactionID: r.PathValue("id"),
+	}
+
+	history, err := h.GetHistory(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (h *Handler) handleListTransactions(w http.ResponseWriter, r *http.Request) {
+	details, err := listTransactionsDetails(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	list, err := h.ListTransactions(requestContext(r), details)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeTransactionList(w, r, http.StatusOK, list)
+}
+
+func listTransactionsDetails(r *http.Request) (ListTransactionsDetails, error) {
+	query := r.URL.Query()
+	errs := &gateway.ValidationError{}
+
+	details := ListTransactionsDetails{
+		Provider:          providerFromRequest(r, ""),
+		MerchantReference: query.Get("merchant_reference"),
+		Currency:          query.Get("currency"),
+		SortBy:            query.Get("sort"),
+		Order:             query.Get("order"),
+		Cursor:            query.Get("cursor"),
+	}
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				details.Statuses = append(details.Statuses, TransactionStatusType(status))
+			}
+		}
+	}
+
+	parseAmount := func(field string) *int64 {
+		value := query.Get(field)
+		if value == "" {
+			return nil
+		}
+
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			errs.Add(field, gateway.CodeInvalidFormat, field+" must be an integer amount in minor units")
+			return nil
+		}
+
+		return &amount
+	}
+
+	parseTime := func(field string) time.Time {
+		value := query.Get(field)
+		if value == "" {
+			return time.Time{}
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			errs.Add(field, gateway.CodeInvalidFormat, field+" must be an RFC 3339 timestamp")
+			return time.Time{}
+		}
+
+		return t.UTC()
+	}
+
+	details.MinAmount = parseAmount("min_amount")
+	details.MaxAmount = parseAmount("max_amount")
+	details.CreatedAfter = parseTime("created_after")
+	details.CreatedBefore = parseTime("created_before")
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			errs.Add("limit", gateway.CodeInvalidFormat, "limit must be an integer")
+		}
+		details.Limit = limit
+	}
+
+	if err := errs.ErrOrNil(); err != nil {
+		return ListTransactionsDetails{}, convertValidationError(ErrInvalidListQuery, errs)
+	}
+
+	return details, nil
+}
+
+func requestContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if actor := r.Header.Get(actorHeader); actor != "" {
+		ctx = WithActor(ctx, actor)
+	}
+	return ctx
+}
+
+func providerFromRequest(r *http.Request, fromBody ProviderType) ProviderType {
+	if provider := r.PathValue("provider"); provider != "" {
+		return ProviderType(provider)
+	}
+	if provider := r.Header.Get(providerHeader); provider != "" {
+		return ProviderType(provider)
+	}
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		return ProviderType(provider)
+	}
+	return fromBody
+}
+
+func idempotencyKey(r *http.Request, fromBody string) string {
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		return key
+	}
+	return fromBody
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedRequest, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeTransaction(w http.ResponseWriter, r *http.Request, code int, status *TransactionStatus) {
+	version, err := schemaVersion(r)
+	if err != nil {
+		version = CurrentSchemaVersion
+	}
+
+	w.Header().Set(schemaVersionHeader, strconv.Itoa(version))
+	writeJSON(w, code, status.AtVersion(version))
+}
+
+func writeTransactionList(w http.ResponseWriter, r *http.Request, code int, list *TransactionList) {
+	version, err := schemaVersion(r)
+	if err != nil {
+		version = CurrentSchemaVersion
+	}
+
+	transactions := make([]any, 0, len(list.Transactions))
+	for _, status := range list.Transactions {
+		transactions = append(transactions, status.AtVersion(version))
+	}
+
+	w.Header().Set(schemaVersionHeader, strconv.Itoa(version))
+	writeJSON(w, code, struct {
+		Transactions []any  `json:"transactions"`
+		NextCursor   string `json:"next_cursor,omitempty"`
+	}{transactions, list.NextCursor})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := httpStatusCode(err)
+
+	message := err.Error()
+	if code == http.StatusInternalServerError {
+		message = ErrInternal.Error()
+	}
+
+	response := errorResponse{Error: message}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		response.Fields = validationErr.Fields
+	}
+
+	var declineErr *DeclineError
+	if errors.As(err, &declineErr) {
+		response.DeclineCode = declineErr.Code
+	}
+
+	writeJSON(w, code, response)
+}
+
+func httpStatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrMalformedRequest),
+		errors.Is(err, ErrUnsupportedSchemaVersion),
+		errors.Is(err, ErrInvalidPaymentDetails),
+		errors.Is(err, ErrInvalidRefundDetails),
+		errors.Is(err, ErrInvalidCaptureDetails),
+		errors.Is(err, ErrInvalidVoidDetails),
+		errors.Is(err, ErrInvalidTransactionID),
+		errors.Is(err, ErrInvalidListQuery),
+		errors.Is(err, ErrInvalidCardDetails),
+		errors.Is(err, ErrInvalidWebhook),
+		errors.Is(err, ErrProviderRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, ErrUnknownProvider),
+		errors.Is(err, ErrTransactionNotFound),
+		errors.Is(err, ErrWebhookNotFound),
+		errors.Is(err, ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVaultUnavailable),
+		errors.Is(err, ErrRoutingUnavailable),
+		errors.Is(err, ErrWebhooksUnavailable):
+		return http.StatusNotImplemented
+	case errors.Is(err, ErrIdempotencyConflict),
+		errors.Is(err, ErrTransactionConflict),
+		errors.Is(err, ErrDeliveryNotDead):
+		return http.StatusConflict
+	case errors.Is(err, ErrProviderUnavailable),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
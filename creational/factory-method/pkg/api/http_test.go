@@ -0,0 +1,361 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"factory-method/internal/payment/gateway"
+	"factory-method/pkg/money"
+)
+
+func goldenTransaction() *TransactionStatus {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	return &TransactionStatus{
+		TransactionID:     "txn-1",
+		Provider:          StripeProvider,
+		Status:            StatusPartiallyRefunded,
+		Amount:            money.New(1000, "USD"),
+		CapturedAmount:    money.New(1000, "USD"),
+		RefundedAmount:    money.New(250, "USD"),
+		RefundableAmount:  money.New(750, "USD"),
+		Fee:               money.New(59, "USD"),
+		MerchantReference: "order-42",
+		Metadata:          map[string]string{"channel": "web"},
+		Refunds: []RefundRecord{{
+			RefundID:          "re-1",
+			Amount:            money.New(250, "USD"),
+			Reason:            "damaged",
+			MerchantReference: "rma-7",
+			Metadata:          map[string]string{"ticket": "T-9"},
+			CreatedAt:         created.Add(time.Hour),
+		}},
+		CreatedAt: created,
+		UpdatedAt: created.Add(time.Hour),
+	}
+}
+
+func TestWriteTransactionGoldenOutput(t *testing.T) {
+	const goldenV2 = `{"schema_version":2,"transaction_id":"txn-1","provider":"stripe","status":"partially_refunded",` +
+		`"amount":{"amount":1000,"currency":"USD"},"captured_amount":{"amount":1000,"currency":"USD"},` +
+		`"refunded_amount":{"amount":250,"currency":"USD"},"refundable_amount":{"amount":750,"currency":"USD"},` +
+		`"fee":{"amount":59,"currency":"USD"},"refunds":[{"refund_id":"re-1","amount":{"amount":250,"currency":"USD"},` +
+		`"reason":"damaged","merchant_reference":"rma-7","metadata":{"ticket":"T-9"},"created_at":"2024-03-01T13:00:00Z"}],` +
+		`"merchant_reference":"order-42","metadata":{"channel":"web"},` +
+		`"created_at":"2024-03-01T12:00:00Z","updated_at":"2024-03-01T13:00:00Z"}`
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{
+			header: "1",
+			want: `{"status":"partially_refunded","amount":{"amount":1000,"currency":"USD"},` +
+				`"captured_amount":{"amount":1000,"currency":"USD"},"refunded_amount":{"amount":250,"currency":"USD"},` +
+				`"refunds":[{"refund_id":"re-1","amount":{"amount":250,"currency":"USD"},"reason":"damaged","created_at":"2024-03-01T13:00:00Z"}]}`,
+		},
+		{
+			header: "2",
+			want:   goldenV2,
+		},
+		{
+			header: "",
+			want:   goldenV2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("version "+tt.header, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/transactions/txn-1", nil)
+			if tt.header != "" {
+				r.Header.Set(schemaVersionHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			writeTransaction(w, r, http.StatusOK, goldenTransaction())
+
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Fatalf("body =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			wantVersion := tt.header
+			if wantVersion == "" {
+				wantVersion = "2"
+			}
+			if got := w.Header().Get(schemaVersionHeader); got != wantVersion {
+				t.Fatalf("%s = %q, want %q", schemaVersionHeader, got, wantVersion)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSchemaVersionIsRejected(t *testing.T) {
+	h := NewHandler(newTestRegistry())
+
+	for _, header := range []string{"0", "3", "latest"} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/providers", nil)
+		r.Header.Set(schemaVersionHeader, header)
+		w := httptest.NewRecorder()
+
+		NewHTTPHandler(h).ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s %q: status = %d, want %d", schemaVersionHeader, header, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestProcessorErrorsMapToHTTPStatus(t *testing.T) {
+	validation := &gateway.ValidationError{}
+	validation.Add("card_number", "invalid_checksum", "card number fails the Luhn check")
+
+	tests := []struct {
+		name        string
+		err         error
+		want        error
+		code        int
+		declineCode string
+		fields      []FieldError
+		message     string
+	}{
+		{
+			name:        "declined",
+			err:         gateway.Declined("stripe", "insufficient_funds", "card has insufficient funds"),
+			want:        ErrPaymentDeclined,
+			code:        http.StatusPaymentRequired,
+			declineCode: "insufficient_funds",
+			message:     "payment declined: card has insufficient funds",
+		},
+		{
+			name:    "invalid request",
+			err:     gateway.NewError(gateway.KindInvalidRequest, "stripe", "amount exceeds the captured amount"),
+			want:    ErrInvalidRefundDetails,
+			code:    http.StatusBadRequest,
+			message: "invalid refund details: amount exceeds the captured amount",
+		},
+		{
+			name:    "validation",
+			err:     fmt.Errorf("stripe: %w", validation),
+			want:    ErrInvalidRefundDetails,
+			code:    http.StatusBadRequest,
+			fields:  []FieldError{{Field: "card_number", Code: "invalid_checksum", Message: "card number fails the Luhn check"}},
+			message: "invalid refund details: card_number: card number fails the Luhn check",
+		},
+		{
+			name:    "not found",
+			err:     gateway.NewError(gateway.KindNotFound, "stripe", "no such transaction txn-1"),
+			want:    ErrTransactionNotFound,
+			code:    http.StatusNotFound,
+			message: ErrTransactionNotFound.Error(),
+		},
+		{
+			name:    "conflict",
+			err:     gateway.ErrVersionConflict,
+			want:    ErrTransactionConflict,
+			code:    http.StatusConflict,
+			message: ErrTransactionConflict.Error() + ": transaction was modified concurrently",
+		},
+		{
+			name:    "idempotency reused",
+			err:     gateway.ErrIdempotencyKeyReused,
+			want:    ErrIdempotencyConflict,
+			code:    http.StatusConflict,
+			message: ErrIdempotencyConflict.Error(),
+		},
+		{
+			name:    "idempotency in progress",
+			err:     gateway.ErrIdempotencyKeyInProgress,
+			want:    ErrIdempotencyConflict,
+			code:    http.StatusConflict,
+			message: ErrIdempotencyConflict.Error(),
+		},
+		{
+			name:    "transient",
+			err:     gateway.Errorf(gateway.KindTransient, "stripe", "dial tcp: %w", errors.New("connection refused")),
+			want:    ErrProviderUnavailable,
+			code:    http.StatusServiceUnavailable,
+			message: ErrProviderUnavailable.Error(),
+		},
+		{
+			name:    "canceled",
+			err:     context.Canceled,
+			want:    context.Canceled,
+			code:    http.StatusServiceUnavailable,
+			message: context.Canceled.Error(),
+		},
+		{
+			name:    "untyped",
+			err:     errors.New("pq: relation transactions does not exist"),
+			want:    ErrInternal,
+			code:    http.StatusInternalServerError,
+			message: ErrInternal.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := convertProcessorError(ErrInvalidRefundDetails, tt.err)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("convertProcessorError = %v, want %v", err, tt.want)
+			}
+
+			w := httptest.NewRecorder()
+			writeError(w, err)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d", w.Code, tt.code)
+			}
+
+			var response errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if response.Error != tt.message {
+				t.Fatalf("error = %q, want %q", response.Error, tt.message)
+			}
+			if response.DeclineCode != tt.declineCode {
+				t.Fatalf("decline code = %q, want %q", response.DeclineCode, tt.declineCode)
+			}
+			if len(response.Fields) != len(tt.fields) || (len(tt.fields) > 0 && response.Fields[0] != tt.fields[0]) {
+				t.Fatalf("fields = %+v, want %+v", response.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+func serveTestRequest(t *testing.T, handler http.Handler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func decodeTestTransaction(t *testing.T, w *httptest.ResponseRecorder) TransactionStatus {
+	t.Helper()
+
+	var status TransactionStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode transaction: %v (body %s)", err, w.Body)
+	}
+
+	return status
+}
+
+func TestHTTPRoutesAndMethods(t *testing.T) {
+	handler := NewHTTPHandler(NewHandler(newTestRegistry(), WithDefaultProvider(StripeProvider)))
+
+	payment := `{"amount":{"amount":1000,"currency":"USD"},"card_number":"4242424242424242",` +
+		`"card_holder":"Jane Doe","expiry_date":"12/40","cvv":"123"}`
+
+	w := serveTestRequest(t, handler, http.MethodPost, "/v1/payments", payment, http.Header{idempotencyKeyHeader: {"order-1"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /v1/payments = %d, want %d (body %s)", w.Code, http.StatusCreated, w.Body)
+	}
+	created := decodeTestTransaction(t, w)
+
+	w = serveTestRequest(t, handler, http.MethodGet, "/v1/transactions/"+created.TransactionID, "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET transaction = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := decodeTestTransaction(t, w); got.TransactionID != created.TransactionID || got.Status != StatusCompleted {
+		t.Fatalf("GET transaction = %s (%s), want %s (completed)", got.TransactionID, got.Status, created.TransactionID)
+	}
+
+	scoped := "/v1/providers/" + string(created.Provider) + "/transactions/" + created.TransactionID
+	if w = serveTestRequest(t, handler, http.MethodGet, scoped, "", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d, want %d", scoped, w.Code, http.StatusOK)
+	}
+
+	tests := []struct {
+		method string
+		target string
+		code   int
+	}{
+		{http.MethodGet, "/v1/providers", http.StatusOK},
+		{http.MethodGet, "/v1/transactions/txn-missing", http.StatusNotFound},
+		{http.MethodGet, "/v1/providers/acme/transactions/" + created.TransactionID, http.StatusNotFound},
+		{http.MethodGet, "/v1/payments", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/v1/transactions/" + created.TransactionID, http.StatusMethodNotAllowed},
+		{http.MethodPut, "/v1/transactions/" + created.TransactionID + "/refunds", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v2/payments", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		if w := serveTestRequest(t, handler, tt.method, tt.target, "", nil); w.Code != tt.code {
+			t.Fatalf("%s %s = %d, want %d", tt.method, tt.target, w.Code, tt.code)
+		}
+	}
+}
+
+func TestDecodeRequestBodies(t *testing.T) {
+	handler := NewHTTPHandler(NewHandler(newTestRegistry(), WithDefaultProvider(StripeProvider)))
+
+	authorization := `{"amount":{"amount":1000,"currency":"USD"},"card_number":"4242424242424242",` +
+		`"card_holder":"Jane Doe","expiry_date":"12/40","cvv":"123"}`
+
+	w := serveTestRequest(t, handler, http.MethodPost, "/v1/authorizations", authorization, http.Header{idempotencyKeyHeader: {"auth-1"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /v1/authorizations = %d, want %d (body %s)", w.Code, http.StatusCreated, w.Body)
+	}
+	authorized := decodeTestTransaction(t, w)
+
+	w = serveTestRequest(t, handler, http.MethodPost, "/v1/transactions/"+authorized.TransactionID+"/capture", "", http.Header{idempotencyKeyHeader: {"capture-1"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("capture with an empty body = %d, want %d (body %s)", w.Code, http.StatusOK, w.Body)
+	}
+	if captured := decodeTestTransaction(t, w); captured.CapturedAmount.Amount() != 1000 {
+		t.Fatalf("captured %s, want the full authorization", captured.CapturedAmount)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		want   error
+		fields bool
+	}{
+		{name: "empty", body: "", code: http.StatusBadRequest, want: ErrInvalidPaymentDetails, fields: true},
+		{name: "syntax", body: `{"amount":`, code: http.StatusBadRequest, want: ErrMalformedRequest},
+		{name: "unknown field", body: `{"card":"4242424242424242"}`, code: http.StatusBadRequest, want: ErrMalformedRequest},
+		{name: "too large", body: `{"description":"` + strings.Repeat("x", maxRequestBodyBytes) + `"}`, code: http.StatusBadRequest, want: ErrMalformedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveTestRequest(t, handler, http.MethodPost, "/v1/payments", tt.body, http.Header{idempotencyKeyHeader: {"order-" + tt.name}})
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.code, w.Body)
+			}
+
+			var response errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if !strings.HasPrefix(response.Error, tt.want.Error()) {
+				t.Fatalf("error = %q, want %q", response.Error, tt.want)
+			}
+			if got := len(response.Fields) > 0; got != tt.fields {
+				t.Fatalf("fields = %+v, want fields: %v", response.Fields, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseAndFormatPerMinorUnit(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		amount   int64
+		format   string
+	}{
+		{"1234", "JPY", 1234, "1234"},
+		{"-1234", "KRW", -1234, "-1234"},
+		{"12.34", "USD", 1234, "12.34"},
+		{"12.3", "eur", 1230, "12.30"},
+		{"12", "GBP", 1200, "12.00"},
+		{"0.05", "USD", 5, "0.05"},
+		{"-0.05", "USD", -5, "-0.05"},
+		{"+7.5", "USD", 750, "7.50"},
+		{" 1.001 ", "KWD", 1001, "1.001"},
+		{"0.5", "BHD", 500, "0.500"},
+		{"-0.007", "OMR", -7, "-0.007"},
+		{"0", "USD", 0, "0.00"},
+		{"-0", "JPY", 0, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			m, err := Parse(tt.value, tt.currency)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if m.Amount() != tt.amount {
+				t.Fatalf("amount = %d, want %d", m.Amount(), tt.amount)
+			}
+			if got := m.Format(); got != tt.format {
+				t.Fatalf("Format = %q, want %q", got, tt.format)
+			}
+
+			round, err := Parse(m.Format(), m.Currency())
+			if err != nil || !round.Equal(m) {
+				t.Fatalf("Parse(Format()) = %v, %v, want %v", round, err, m)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidAmounts(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		want     error
+	}{
+		{"1.5", "JPY", ErrInvalidAmount},
+		{"1.234", "USD", ErrInvalidAmount},
+		{"1.2345", "KWD", ErrInvalidAmount},
+		{"", "USD", ErrInvalidAmount},
+		{"-", "USD", ErrInvalidAmount},
+		{".50", "USD", ErrInvalidAmount},
+		{"1.", "USD", ErrInvalidAmount},
+		{"1,50", "USD", ErrInvalidAmount},
+		{"--1", "USD", ErrInvalidAmount},
+		{"+-1", "USD", ErrInvalidAmount},
+		{"1e3", "USD", ErrInvalidAmount},
+		{"1", "XXX", ErrUnknownCurrency},
+		{"9223372036854775808", "JPY", ErrOverflow},
+		{"-9223372036854775809", "JPY", ErrOverflow},
+		{"92233720368547758.08", "USD", ErrOverflow},
+		{"-92233720368547758.09", "USD", ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			if m, err := Parse(tt.value, tt.currency); !errors.Is(err, tt.want) {
+				t.Fatalf("Parse = %v, %v, want %v", m, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAndFormatInt64Boundaries(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		amount   int64
+	}{
+		{"9223372036854775807", "JPY", math.MaxInt64},
+		{"-9223372036854775808", "JPY", math.MinInt64},
+		{"92233720368547758.07", "USD", math.MaxInt64},
+		{"-92233720368547758.08", "USD", math.MinInt64},
+		{"-9223372036854775.808", "KWD", math.MinInt64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value+" "+tt.currency, func(t *testing.T) {
+			m, err := Parse(tt.value, tt.currency)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if m.Amount() != tt.amount {
+				t.Fatalf("amount = %d, want %d", m.Amount(), tt.amount)
+			}
+			if got := New(tt.amount, tt.currency).Format(); got != tt.value {
+				t.Fatalf("Format = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	max, min := New(math.MaxInt64, "USD"), New(math.MinInt64, "USD")
+
+	if _, err := max.Add(New(1, "USD")); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("MaxInt64 + 1 = %v, want ErrOverflow", err)
+	}
+	if _, err := min.Add(New(-1, "USD")); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("MinInt64 - 1 = %v, want ErrOverflow", err)
+	}
+	if _, err := Zero("USD").Sub(min); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("0 - MinInt64 = %v, want ErrOverflow", err)
+	}
+	if got, err := min.Sub(New(-1, "USD")); err != nil || got.Amount() != math.MinInt64+1 {
+		t.Fatalf("MinInt64 - (-1) = %v, %v", got, err)
+	}
+	if _, err := max.Add(New(1, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("USD + EUR = %v, want ErrCurrencyMismatch", err)
+	}
+}